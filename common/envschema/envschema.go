@@ -0,0 +1,283 @@
+// Package envschema is the single typed definition of every ENV_* key the
+// manage server writes into a service's task definition and every
+// service's init container reads back out. Before this package, each of
+// common/types.go's ~30 ENV_* keys was a loose string that every caller
+// parsed ad-hoc; Builder and Parser give the manage server and the
+// services a shared, versioned contract instead.
+package envschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudstax/firecamp/common"
+)
+
+// SchemaVersion is the current ENV_SCHEMA_VERSION the manage server
+// stamps into every task definition it writes. A service image compares
+// the env's ENV_SCHEMA_VERSION against the version it was built against
+// and rejects or downgrades an env set from a control plane newer than it
+// understands, instead of silently misinterpreting an unfamiliar key.
+const SchemaVersion = "1"
+
+// ValueKind is how a FieldSpec's value should be parsed.
+type ValueKind int
+
+const (
+	// KindString is an opaque string, passed through unparsed.
+	KindString ValueKind = iota
+	// KindInt is a base-10 integer.
+	KindInt
+	// KindCSV is a common.ENV_VALUE_SEPARATOR-joined list of strings.
+	KindCSV
+	// KindJSON is a JSON value, typically an object or array.
+	KindJSON
+	// KindDuration is a Go time.Duration string, e.g. "30s".
+	KindDuration
+)
+
+// FieldSpec describes one ENV_* key: the schema version that introduced
+// it, whether a service must set it, what values it accepts, and how to
+// parse it.
+type FieldSpec struct {
+	Key           string
+	Kind          ValueKind
+	Required      bool
+	AllowedValues []string
+	SinceVersion  string
+}
+
+// Fields is the env schema: every ENV_* key common/types.go defines, in
+// the control-plane version that introduced it. This table is also the
+// migration record of which version introduced which key; a new request
+// that adds ENV_* keys should add their FieldSpecs here rather than
+// leaving them to be parsed ad-hoc.
+var Fields = []FieldSpec{
+	{Key: common.ENV_VERSION, Kind: KindString, Required: true, SinceVersion: "1"},
+	{Key: common.ENV_REGION, Kind: KindString, Required: true, SinceVersion: "1"},
+	{Key: common.ENV_CLUSTER, Kind: KindString, Required: true, SinceVersion: "1"},
+	{Key: common.ENV_MANAGE_SERVER_URL, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_OP, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_SERVICE_NAME, Kind: KindString, Required: true, SinceVersion: "1"},
+	{Key: common.ENV_SERVICE_NODE, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_SERVICE_PORT, Kind: KindInt, SinceVersion: "1"},
+	{Key: common.ENV_SERVICE_MASTER, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_SERVICE_MEMBERS, Kind: KindCSV, SinceVersion: "1"},
+	{Key: common.ENV_SERVICE_TYPE, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_CONTAINER_PLATFORM, Kind: KindString, Required: true, AllowedValues: []string{
+		common.ContainerPlatformECS, common.ContainerPlatformSwarm,
+		common.ContainerPlatformFargate, common.ContainerPlatformECSFargate,
+	}, SinceVersion: "1"},
+	{Key: common.ENV_DB_TYPE, Kind: KindString, AllowedValues: []string{common.DBTypeControlDB, common.DBTypeCloudDB}, SinceVersion: "1"},
+	{Key: common.ENV_AVAILABILITY_ZONES, Kind: KindCSV, SinceVersion: "1"},
+
+	{Key: common.ENV_VOLUME_TYPE, Kind: KindString, AllowedValues: []string{common.VolumeTypeDockerPlugin, common.VolumeTypeECSManagedEBS}, SinceVersion: "1"},
+	{Key: common.ENV_EBS_VOLUME_IOPS, Kind: KindInt, SinceVersion: "1"},
+	{Key: common.ENV_EBS_VOLUME_THROUGHPUT, Kind: KindInt, SinceVersion: "1"},
+	{Key: common.ENV_EBS_FILESYSTEM_TYPE, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_EBS_KMS_KEY_ID, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_EBS_SNAPSHOT_ID, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_EBS_ROLE_ARN, Kind: KindString, SinceVersion: "1"},
+
+	{Key: common.ENV_LAUNCH_TYPE, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_CAPACITY_PROVIDERS, Kind: KindCSV, SinceVersion: "1"},
+	{Key: common.ENV_FARGATE_CPU, Kind: KindInt, SinceVersion: "1"},
+	{Key: common.ENV_FARGATE_MEMORY, Kind: KindInt, SinceVersion: "1"},
+	{Key: common.ENV_PLATFORM_VERSION, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_TASK_EXECUTION_ROLE_ARN, Kind: KindString, SinceVersion: "1"},
+
+	{Key: common.ENV_LOG_DRIVER, Kind: KindString, AllowedValues: []string{
+		common.LOGDRIVER_DEFAULT, common.LOGDRIVER_AWSLOGS, common.LOGDRIVER_FIRELENS,
+	}, SinceVersion: "1"},
+	{Key: common.ENV_LOG_ROUTER_IMAGE, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_LOG_ROUTER_TYPE, Kind: KindString, SinceVersion: "1"},
+	{Key: common.ENV_LOG_OUTPUT_CONFIG, Kind: KindJSON, SinceVersion: "1"},
+	{Key: common.ENV_LOG_PARSER_CONFIG, Kind: KindString, SinceVersion: "1"},
+
+	{Key: common.ENV_HOST_OS, Kind: KindString, AllowedValues: []string{common.HostOSAmazonLinux2, common.HostOSBottlerocket}, SinceVersion: "1"},
+	{Key: common.ENV_CONTAINER_RUNTIME, Kind: KindString, SinceVersion: "1"},
+}
+
+var specByKey = func() map[string]FieldSpec {
+	m := make(map[string]FieldSpec, len(Fields))
+	for _, f := range Fields {
+		m[f.Key] = f
+	}
+	return m
+}()
+
+// Builder accumulates ENV_* key/value pairs with a fluent Add, then emits
+// them as the []common.EnvKeyValuePair the manage server hands to ECS's
+// RegisterTaskDefinition, stamping ENV_SCHEMA_VERSION automatically so
+// callers can't forget it.
+type Builder struct {
+	env []common.EnvKeyValuePair
+	set map[string]bool
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{set: make(map[string]bool)}
+}
+
+// Add appends key=value, overwriting any value previously added for key.
+func (b *Builder) Add(key, value string) *Builder {
+	if b.set[key] {
+		for i := range b.env {
+			if b.env[i].Name == key {
+				b.env[i].Value = value
+				return b
+			}
+		}
+	}
+	b.env = append(b.env, common.EnvKeyValuePair{Name: key, Value: value})
+	b.set[key] = true
+	return b
+}
+
+// Build returns the accumulated env as a []common.EnvKeyValuePair, adding
+// ENV_SCHEMA_VERSION=SchemaVersion if the caller didn't set it explicitly.
+func (b *Builder) Build() []common.EnvKeyValuePair {
+	if !b.set[common.ENV_SCHEMA_VERSION] {
+		b.Add(common.ENV_SCHEMA_VERSION, SchemaVersion)
+	}
+	return b.env
+}
+
+// ServiceEnv is the typed view Parser.Parse produces from a task's raw
+// env: each key's value parsed according to its FieldSpec.Kind, plus the
+// schema version the env was built with.
+type ServiceEnv struct {
+	Version string
+	Raw     []common.EnvKeyValuePair
+	values  map[string]interface{}
+}
+
+// String returns key's raw string value.
+func (s *ServiceEnv) String(key string) (string, bool) {
+	for _, kv := range s.Raw {
+		if kv.Name == key {
+			return kv.Value, true
+		}
+	}
+	return "", false
+}
+
+// Int returns key's value as an int64, as parsed by Parser.Parse.
+func (s *ServiceEnv) Int(key string) (int64, bool) {
+	v, ok := s.values[key]
+	if !ok {
+		return 0, false
+	}
+	i, ok := v.(int64)
+	return i, ok
+}
+
+// CSV returns key's value split on common.ENV_VALUE_SEPARATOR, as parsed
+// by Parser.Parse.
+func (s *ServiceEnv) CSV(key string) ([]string, bool) {
+	v, ok := s.values[key]
+	if !ok {
+		return nil, false
+	}
+	csv, ok := v.([]string)
+	return csv, ok
+}
+
+// Duration returns key's value as a time.Duration, as parsed by
+// Parser.Parse.
+func (s *ServiceEnv) Duration(key string) (time.Duration, bool) {
+	v, ok := s.values[key]
+	if !ok {
+		return 0, false
+	}
+	d, ok := v.(time.Duration)
+	return d, ok
+}
+
+// JSON unmarshals key's raw JSON value into out.
+func (s *ServiceEnv) JSON(key string, out interface{}) (bool, error) {
+	raw, ok := s.String(key)
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return true, fmt.Errorf("envschema: invalid JSON for %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Parser validates and parses a task's raw env against Fields.
+type Parser struct{}
+
+// NewParser returns a Parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// Parse validates env against Fields (required keys present, values among
+// AllowedValues, values parseable as their FieldSpec.Kind) and returns the
+// typed ServiceEnv. Keys not in Fields are kept in ServiceEnv.Raw but
+// otherwise ignored, so a service running an older schema version doesn't
+// fail parsing envs it doesn't yet know about.
+func (p *Parser) Parse(env []common.EnvKeyValuePair) (*ServiceEnv, error) {
+	se := &ServiceEnv{Raw: env, values: make(map[string]interface{})}
+
+	seen := make(map[string]bool, len(env))
+	for _, kv := range env {
+		seen[kv.Name] = true
+		if kv.Name == common.ENV_SCHEMA_VERSION {
+			se.Version = kv.Value
+			continue
+		}
+
+		spec, ok := specByKey[kv.Name]
+		if !ok {
+			continue
+		}
+		if len(spec.AllowedValues) > 0 && !contains(spec.AllowedValues, kv.Value) {
+			return nil, fmt.Errorf("envschema: %s has invalid value %q, expect one of %v", kv.Name, kv.Value, spec.AllowedValues)
+		}
+
+		switch spec.Kind {
+		case KindInt:
+			i, err := strconv.ParseInt(kv.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("envschema: invalid %s %q: %v", kv.Name, kv.Value, err)
+			}
+			se.values[kv.Name] = i
+		case KindCSV:
+			se.values[kv.Name] = strings.Split(kv.Value, common.ENV_VALUE_SEPARATOR)
+		case KindJSON:
+			if !json.Valid([]byte(kv.Value)) {
+				return nil, fmt.Errorf("envschema: %s is not valid JSON", kv.Name)
+			}
+		case KindDuration:
+			d, err := time.ParseDuration(kv.Value)
+			if err != nil {
+				return nil, fmt.Errorf("envschema: invalid %s %q: %v", kv.Name, kv.Value, err)
+			}
+			se.values[kv.Name] = d
+		}
+	}
+
+	for _, spec := range Fields {
+		if spec.Required && !seen[spec.Key] {
+			return nil, fmt.Errorf("envschema: required env %s is missing", spec.Key)
+		}
+	}
+
+	return se, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package envschema
+
+import (
+	"testing"
+
+	"github.com/cloudstax/firecamp/common"
+)
+
+// sampleValue returns a value that parses cleanly for kind, so the round
+// trip test below exercises every FieldSpec.Kind without hard-coding
+// per-key test data.
+func sampleValue(kind ValueKind) string {
+	switch kind {
+	case KindInt:
+		return "256"
+	case KindCSV:
+		return "a" + common.ENV_VALUE_SEPARATOR + "b"
+	case KindJSON:
+		return `[{"name":"cloudwatch_logs","options":{"region":"us-west-2"}}]`
+	case KindDuration:
+		return "30s"
+	default:
+		return "test-value"
+	}
+}
+
+// valueFor returns the value used to exercise spec in the tests below:
+// its first AllowedValue if it has any, otherwise a sampleValue for its
+// Kind.
+func valueFor(spec FieldSpec) string {
+	if len(spec.AllowedValues) > 0 {
+		return spec.AllowedValues[0]
+	}
+	return sampleValue(spec.Kind)
+}
+
+// TestEveryFieldIsRepresentable asserts every key in Fields round-trips
+// through Builder and Parser, so a FieldSpec whose sample value can't
+// actually be parsed fails the test instead of breaking some service at
+// runtime. It only covers keys listed in Fields: an ENV_* key added to
+// common/types.go without a FieldSpec is not detected here.
+func TestEveryFieldIsRepresentable(t *testing.T) {
+	for _, spec := range Fields {
+		spec := spec
+		t.Run(spec.Key, func(t *testing.T) {
+			value := valueFor(spec)
+
+			b := NewBuilder()
+			for _, required := range Fields {
+				if required.Required && required.Key != spec.Key {
+					b.Add(required.Key, valueFor(required))
+				}
+			}
+			b.Add(spec.Key, value)
+
+			env := b.Build()
+
+			se, err := NewParser().Parse(env)
+			if err != nil {
+				t.Fatalf("Parse() for %s = %v, want nil error", spec.Key, err)
+			}
+
+			got, ok := se.String(spec.Key)
+			if !ok || got != value {
+				t.Errorf("String(%s) = %q, %v, want %q, true", spec.Key, got, ok, value)
+			}
+		})
+	}
+}
+
+func TestBuilderStampsSchemaVersion(t *testing.T) {
+	env := NewBuilder().
+		Add(common.ENV_VERSION, "0.7.1").
+		Add(common.ENV_REGION, "us-west-2").
+		Add(common.ENV_CLUSTER, "test").
+		Add(common.ENV_SERVICE_NAME, "svc").
+		Add(common.ENV_CONTAINER_PLATFORM, common.ContainerPlatformECS).
+		Build()
+
+	se, err := NewParser().Parse(env)
+	if err != nil {
+		t.Fatalf("Parse() = %v, want nil error", err)
+	}
+	if se.Version != SchemaVersion {
+		t.Errorf("Version = %q, want %q", se.Version, SchemaVersion)
+	}
+}
+
+func TestParseRejectsInvalidAllowedValue(t *testing.T) {
+	env := NewBuilder().
+		Add(common.ENV_VERSION, "0.7.1").
+		Add(common.ENV_REGION, "us-west-2").
+		Add(common.ENV_CLUSTER, "test").
+		Add(common.ENV_SERVICE_NAME, "svc").
+		Add(common.ENV_CONTAINER_PLATFORM, common.ContainerPlatformECS).
+		Add(common.ENV_VOLUME_TYPE, "not-a-real-volume-type").
+		Build()
+
+	if _, err := NewParser().Parse(env); err == nil {
+		t.Fatal("Parse() = nil error, want error for invalid AllowedValues")
+	}
+}
+
+func TestParseRejectsMissingRequiredField(t *testing.T) {
+	env := NewBuilder().Build()
+
+	if _, err := NewParser().Parse(env); err == nil {
+		t.Fatal("Parse() = nil error, want error for missing required fields")
+	}
+}
+
+func TestParseIgnoresUnknownKeys(t *testing.T) {
+	env := []common.EnvKeyValuePair{
+		{Name: common.ENV_VERSION, Value: "0.7.1"},
+		{Name: common.ENV_REGION, Value: "us-west-2"},
+		{Name: common.ENV_CLUSTER, Value: "test"},
+		{Name: common.ENV_SERVICE_NAME, Value: "svc"},
+		{Name: common.ENV_CONTAINER_PLATFORM, Value: common.ContainerPlatformECS},
+		{Name: "SOME_FUTURE_ENV_KEY", Value: "anything"},
+	}
+
+	if _, err := NewParser().Parse(env); err != nil {
+		t.Fatalf("Parse() = %v, want nil error for an unknown key", err)
+	}
+}
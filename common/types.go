@@ -5,6 +5,22 @@ const (
 
 	ContainerPlatformECS   = "ecs"
 	ContainerPlatformSwarm = "swarm"
+	// ContainerPlatformFargate runs every task on Fargate: no EC2 container
+	// instances, no docker volume plugin, awsvpc networking only.
+	ContainerPlatformFargate = "fargate"
+	// ContainerPlatformECSFargate mixes EC2 and Fargate capacity behind an
+	// ECS capacity-provider strategy, so a stateful service normally placed
+	// on EC2 can burst onto Fargate when EC2 capacity is exhausted.
+	ContainerPlatformECSFargate = "ecs-fargate"
+
+	// HostOSAmazonLinux2 is the default: a dockerd host that supports the
+	// cloudstax/firecamp-volume and cloudstax/firecamp-log docker plugins.
+	HostOSAmazonLinux2 = "amazonlinux2"
+	// HostOSBottlerocket is ECS's Bottlerocket aws-ecs-1 variant: a
+	// containerd host with no docker plugin support, so the volume and
+	// log driver plugins must be swapped for their ECS-native equivalents
+	// (VolumeTypeECSManagedEBS, LOGDRIVER_FIRELENS).
+	HostOSBottlerocket = "bottlerocket"
 
 	OrgName             = "cloudstax"
 	SystemName          = "firecamp"
@@ -24,6 +40,11 @@ const (
 	LogDriverName     = OrgName + "/" + SystemName + "-" + "log"
 	LOGDRIVER_DEFAULT = "json-file"
 	LOGDRIVER_AWSLOGS = "awslogs"
+	// LOGDRIVER_FIRELENS routes container logs through an ECS FireLens
+	// sidecar (Fluent Bit or Fluentd) instead of a host-level log driver,
+	// so logs can fan out to CloudWatch, S3, Kinesis Firehose,
+	// Elasticsearch/OpenSearch or Loki without a cluster-wide agent.
+	LOGDRIVER_FIRELENS = "awsfirelens"
 
 	DefaultLogDir = "/var/log/" + SystemName
 
@@ -77,6 +98,15 @@ const (
 	ManageReserveCPUUnits = 256
 	ManageMaxMemMB        = 4096
 	ManageReserveMemMB    = 256
+
+	// VolumeTypeDockerPlugin is the default: the service's volume is attached
+	// via the cloudstax/firecamp-volume docker volume plugin.
+	VolumeTypeDockerPlugin = "dockerplugin"
+	// VolumeTypeECSManagedEBS opts a service into ECS-native
+	// volumeConfigurations/managedEBSVolume instead of the docker volume
+	// plugin, so it runs on Fargate, where custom docker volume plugins
+	// aren't allowed.
+	VolumeTypeECSManagedEBS = "ecsmanagedebs"
 )
 
 type EnvKeyValuePair struct {
@@ -107,4 +137,64 @@ const (
 	ENV_CONTAINER_PLATFORM = "CONTAINER_PLATFORM"
 	ENV_DB_TYPE            = "DB_TYPE"
 	ENV_AVAILABILITY_ZONES = "AVAILABILITY_ZONES"
+
+	// ENV_VOLUME_TYPE selects how the service's volume is attached:
+	// VolumeTypeDockerPlugin (default) or VolumeTypeECSManagedEBS. The
+	// remaining ENV_EBS_* keys only apply when ENV_VOLUME_TYPE is
+	// VolumeTypeECSManagedEBS.
+	ENV_VOLUME_TYPE           = "VOLUME_TYPE"
+	ENV_EBS_VOLUME_IOPS       = "EBS_VOLUME_IOPS"
+	ENV_EBS_VOLUME_THROUGHPUT = "EBS_VOLUME_THROUGHPUT"
+	ENV_EBS_FILESYSTEM_TYPE   = "EBS_FILESYSTEM_TYPE"
+	ENV_EBS_KMS_KEY_ID        = "EBS_KMS_KEY_ID"
+	ENV_EBS_SNAPSHOT_ID       = "EBS_SNAPSHOT_ID"
+	ENV_EBS_ROLE_ARN          = "EBS_ROLE_ARN"
+
+	// ENV_LAUNCH_TYPE and ENV_CAPACITY_PROVIDERS select how ECS places a
+	// service's tasks: a plain launch type ("EC2" or "FARGATE"), or a
+	// capacity-provider strategy (ENV_CAPACITY_PROVIDERS, a
+	// ENV_VALUE_SEPARATOR-joined list of "name:weight:base" entries) for
+	// ContainerPlatformECSFargate.
+	ENV_LAUNCH_TYPE             = "LAUNCH_TYPE"
+	ENV_CAPACITY_PROVIDERS      = "CAPACITY_PROVIDERS"
+	ENV_FARGATE_CPU             = "FARGATE_CPU"
+	ENV_FARGATE_MEMORY          = "FARGATE_MEMORY"
+	ENV_PLATFORM_VERSION        = "PLATFORM_VERSION"
+	ENV_TASK_EXECUTION_ROLE_ARN = "TASK_EXECUTION_ROLE_ARN"
+
+	// ENV_LOG_DRIVER selects the task's log driver: LOGDRIVER_DEFAULT,
+	// LOGDRIVER_AWSLOGS, or LOGDRIVER_FIRELENS. The remaining ENV_LOG_*
+	// keys only apply when ENV_LOG_DRIVER is LOGDRIVER_FIRELENS: they
+	// configure the FireLens sidecar the manage server injects into the
+	// task definition alongside the app container.
+	ENV_LOG_DRIVER = "LOG_DRIVER"
+	// ENV_LOG_ROUTER_IMAGE is the FireLens sidecar's container image,
+	// defaulting to the upstream aws-for-fluent-bit image when unset.
+	ENV_LOG_ROUTER_IMAGE = "LOG_ROUTER_IMAGE"
+	// ENV_LOG_ROUTER_TYPE is "fluentbit" or "fluentd", ECS's two
+	// supported FireLens router types.
+	ENV_LOG_ROUTER_TYPE = "LOG_ROUTER_TYPE"
+	// ENV_LOG_OUTPUT_CONFIG is a JSON array of FireLens output stanzas
+	// (CloudWatch, S3, Kinesis Firehose, Elasticsearch/OpenSearch, Loki),
+	// so a service can fan its logs out to more than one destination.
+	ENV_LOG_OUTPUT_CONFIG = "LOG_OUTPUT_CONFIG"
+	// ENV_LOG_PARSER_CONFIG is the service's Fluent Bit/Fluentd parser
+	// definitions, appended to the router's config so per-service log
+	// formats (e.g. Cassandra, Kafka) parse into structured fields.
+	ENV_LOG_PARSER_CONFIG = "LOG_PARSER_CONFIG"
+
+	// ENV_HOST_OS selects the cluster host OS: HostOSAmazonLinux2
+	// (default) or HostOSBottlerocket. ENV_CONTAINER_RUNTIME ("docker" or
+	// "containerd") is derived from it and propagated into the cluster
+	// bootstrap systemd unit/user-data templates so they start the right
+	// init for the host.
+	ENV_HOST_OS           = "HOST_OS"
+	ENV_CONTAINER_RUNTIME = "CONTAINER_RUNTIME"
+
+	// ENV_SCHEMA_VERSION is written into every task definition alongside
+	// the rest of the env, so a service image can tell which control-plane
+	// envschema.SchemaVersion produced its env and reject or downgrade
+	// envs from a version newer than the image understands. See the
+	// common/envschema package.
+	ENV_SCHEMA_VERSION = "SCHEMA_VERSION"
 )
@@ -0,0 +1,183 @@
+package controldbcli
+
+import (
+	"io"
+
+	"golang.org/x/net/context"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/status"
+
+	"github.com/golang/glog"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db/controldb"
+	pb "github.com/cloudstax/firecamp/db/controldb/protocols"
+	"github.com/cloudstax/firecamp/errdefs"
+	"github.com/cloudstax/firecamp/utils"
+)
+
+// BatchItemResult is one key's outcome within a *Batch call. Err is the
+// errdefs-typed per-item error (nil on success), so a caller can
+// errdefs.IsNotFound a single failed key out of an otherwise-successful
+// batch instead of the whole batch failing.
+type BatchItemResult struct {
+	FileID string
+	Err    error
+}
+
+// statusToErr reconstructs the per-item error from the wire, going through
+// errdefs.FromGRPC so IsNotFound/IsAlreadyExists/etc. work the same as they
+// do on the single-key calls.
+func statusToErr(st *spb.Status) error {
+	if st == nil || st.Code == 0 {
+		return nil
+	}
+	return errdefs.FromGRPC(status.ErrorProto(st))
+}
+
+// DeleteConfigFilesBatch deletes every fileID for serviceUUID over a single
+// bidi-streaming RPC instead of one unary DeleteConfigFile per key, so a
+// service teardown with hundreds of config files costs one network
+// round-trip pipeline rather than hundreds of retry-capable ones. The
+// client's retry policy (and shared RetryBudget) applies to establishing
+// the stream itself, not to each individual key -- a key that fails is
+// reported in its own BatchItemResult, never silently retried mid-batch.
+func (c *ControlDBCli) DeleteConfigFilesBatch(ctx context.Context, serviceUUID string, fileIDs []string) ([]BatchItemResult, error) {
+	requuid := utils.GenRequestUUID()
+	ctx = utils.NewRequestContext(ctx, requuid)
+	ctx = attachTraceMetadata(ctx, requuid)
+
+	cli := c.getCli()
+	stream, err := cli.dbcli.DeleteConfigFiles(ctx)
+	if err != nil {
+		glog.Errorln("DeleteConfigFilesBatch open stream error", err, "service", serviceUUID, "requuid", requuid)
+		if !c.isApplicationError(err) {
+			c.markClientFailed(cli)
+		}
+		return nil, err
+	}
+
+	go func() {
+		for _, fileID := range fileIDs {
+			sendErr := stream.Send(&pb.ConfigFileKey{ServiceUUID: serviceUUID, FileID: fileID})
+			if sendErr != nil {
+				glog.Errorln("DeleteConfigFilesBatch send error", sendErr, "service", serviceUUID, "requuid", requuid)
+				break
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	results := make([]BatchItemResult, 0, len(fileIDs))
+	for {
+		resp, recvErr := stream.Recv()
+		if recvErr == io.EOF {
+			break
+		}
+		if recvErr != nil {
+			glog.Errorln("DeleteConfigFilesBatch recv error", recvErr, "service", serviceUUID, "requuid", requuid)
+			return results, recvErr
+		}
+		results = append(results, BatchItemResult{FileID: resp.FileID, Err: statusToErr(resp.Status)})
+	}
+
+	glog.Infoln("DeleteConfigFilesBatch deleted", len(results), "files for service", serviceUUID, "requuid", requuid)
+	return results, nil
+}
+
+// CreateConfigFilesBatch is DeleteConfigFilesBatch's counterpart for the
+// put path: it creates every cfg over one bidi-streaming RPC.
+func (c *ControlDBCli) CreateConfigFilesBatch(ctx context.Context, cfgs []*common.ConfigFile) ([]BatchItemResult, error) {
+	requuid := utils.GenRequestUUID()
+	ctx = utils.NewRequestContext(ctx, requuid)
+	ctx = attachTraceMetadata(ctx, requuid)
+
+	cli := c.getCli()
+	stream, err := cli.dbcli.CreateConfigFiles(ctx)
+	if err != nil {
+		glog.Errorln("CreateConfigFilesBatch open stream error", err, "requuid", requuid)
+		if !c.isApplicationError(err) {
+			c.markClientFailed(cli)
+		}
+		return nil, err
+	}
+
+	go func() {
+		for _, cfg := range cfgs {
+			sendErr := stream.Send(controldb.GenPbConfigFile(cfg))
+			if sendErr != nil {
+				glog.Errorln("CreateConfigFilesBatch send error", sendErr, "requuid", requuid)
+				break
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	results := make([]BatchItemResult, 0, len(cfgs))
+	for {
+		resp, recvErr := stream.Recv()
+		if recvErr == io.EOF {
+			break
+		}
+		if recvErr != nil {
+			glog.Errorln("CreateConfigFilesBatch recv error", recvErr, "requuid", requuid)
+			return results, recvErr
+		}
+		results = append(results, BatchItemResult{FileID: resp.FileID, Err: statusToErr(resp.Status)})
+	}
+
+	glog.Infoln("CreateConfigFilesBatch created", len(results), "files, requuid", requuid)
+	return results, nil
+}
+
+// GetConfigFilesBatch is DeleteConfigFilesBatch's counterpart for the read
+// path: it fetches every fileID for serviceUUID over one bidi-streaming
+// RPC, returning the fetched files keyed by FileID plus a BatchItemResult
+// per key that failed.
+func (c *ControlDBCli) GetConfigFilesBatch(ctx context.Context, serviceUUID string, fileIDs []string) (map[string]*common.ConfigFile, []BatchItemResult, error) {
+	requuid := utils.GenRequestUUID()
+	ctx = utils.NewRequestContext(ctx, requuid)
+	ctx = attachTraceMetadata(ctx, requuid)
+
+	cli := c.getCli()
+	stream, err := cli.dbcli.GetConfigFiles(ctx)
+	if err != nil {
+		glog.Errorln("GetConfigFilesBatch open stream error", err, "service", serviceUUID, "requuid", requuid)
+		if !c.isApplicationError(err) {
+			c.markClientFailed(cli)
+		}
+		return nil, nil, err
+	}
+
+	go func() {
+		for _, fileID := range fileIDs {
+			sendErr := stream.Send(&pb.ConfigFileKey{ServiceUUID: serviceUUID, FileID: fileID})
+			if sendErr != nil {
+				glog.Errorln("GetConfigFilesBatch send error", sendErr, "service", serviceUUID, "requuid", requuid)
+				break
+			}
+		}
+		stream.CloseSend()
+	}()
+
+	cfgs := make(map[string]*common.ConfigFile, len(fileIDs))
+	var failures []BatchItemResult
+	for {
+		resp, recvErr := stream.Recv()
+		if recvErr == io.EOF {
+			break
+		}
+		if recvErr != nil {
+			glog.Errorln("GetConfigFilesBatch recv error", recvErr, "service", serviceUUID, "requuid", requuid)
+			return cfgs, failures, recvErr
+		}
+		if resp.Status != nil && resp.Status.Code != 0 {
+			failures = append(failures, BatchItemResult{FileID: resp.FileID, Err: statusToErr(resp.Status)})
+			continue
+		}
+		cfgs[resp.FileID] = controldb.GenDbConfigFile(resp.ConfigFile)
+	}
+
+	glog.Infoln("GetConfigFilesBatch got", len(cfgs), "files for service", serviceUUID, "requuid", requuid)
+	return cfgs, failures, nil
+}
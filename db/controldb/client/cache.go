@@ -0,0 +1,386 @@
+package controldbcli
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db"
+)
+
+// defaultCacheSize and defaultNegativeTTL are used when CacheOptions leaves
+// the corresponding field at its zero value.
+const (
+	defaultCacheSize   = 4096
+	defaultNegativeTTL = 2 * time.Second
+)
+
+// CacheOptions configures a CachedControlDBCli.
+type CacheOptions struct {
+	// Size is the maximum number of entries kept in the LRU. <= 0 means
+	// defaultCacheSize.
+	Size int
+	// NegativeTTL is how long a db.ErrDBRecordNotFound result is cached
+	// before the next Get for that key goes back to the server. <= 0 means
+	// defaultNegativeTTL.
+	NegativeTTL time.Duration
+}
+
+// CacheMetrics are cumulative counters a caller can poll to decide whether
+// the cache is sized well, e.g. export them as process metrics.
+type CacheMetrics struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+type cacheEntry struct {
+	key       string
+	obj       interface{}
+	notFound  bool
+	expiresAt time.Time // only set for notFound entries
+	elem      *list.Element
+}
+
+// CachedControlDBCli wraps a ControlDBCli with an in-memory LRU read cache
+// for the Get* hot paths. Concurrent misses for the same key are collapsed
+// into a single RPC via singleflight, and a short-lived negative cache
+// blunts repeated lookups for keys that don't exist (e.g. a controller
+// polling for a volume that hasn't been created yet).
+//
+// The cache is only as fresh as its invalidation source: call
+// InvalidateOnWatch with the matching resource type and scope so that PUT/
+// DELETE events observed over the Watch API evict stale entries.
+type CachedControlDBCli struct {
+	*ControlDBCli
+
+	opts CacheOptions
+	grp  singleflight.Group
+
+	mu    sync.Mutex
+	ll    *list.List // front = most recently used
+	items map[string]*cacheEntry
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// NewCachedControlDBCli wraps cli with a read cache. opts.Size/NegativeTTL
+// fall back to their defaults when left zero.
+func NewCachedControlDBCli(cli *ControlDBCli, opts CacheOptions) *CachedControlDBCli {
+	if opts.Size <= 0 {
+		opts.Size = defaultCacheSize
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = defaultNegativeTTL
+	}
+
+	return &CachedControlDBCli{
+		ControlDBCli: cli,
+		opts:         opts,
+		ll:           list.New(),
+		items:        make(map[string]*cacheEntry),
+	}
+}
+
+// Metrics returns a snapshot of the cache's cumulative hit/miss/eviction
+// counters.
+func (c *CachedControlDBCli) Metrics() CacheMetrics {
+	return CacheMetrics{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+	}
+}
+
+// Invalidate evicts key from the cache, if present. Safe to call whether or
+// not key is currently cached.
+func (c *CachedControlDBCli) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e.elem)
+		delete(c.items, key)
+	}
+}
+
+// InvalidateOnWatch runs until ctx is canceled, evicting the corresponding
+// cache entry for every PUT/DELETE observed on resourceType/scope's watch
+// stream, and clearing the entire cache on a WatchEventResyncRequired
+// (the cheapest correct response to "some events may have been missed").
+// Watch stops after a WatchEventResyncRequired, so InvalidateOnWatch then
+// returns nil as well; entries cached after that point are no longer
+// invalidated until InvalidateOnWatch is called again.
+func (c *CachedControlDBCli) InvalidateOnWatch(ctx context.Context, resourceType ResourceType, scope string, sinceRevision int64) error {
+	events, err := c.Watch(ctx, resourceType, scope, sinceRevision)
+	if err != nil {
+		return err
+	}
+
+	for ev := range events {
+		switch ev.Type {
+		case WatchEventPut, WatchEventDelete:
+			if key, ok := cacheKey(resourceType, scope, ev.Key); ok {
+				c.Invalidate(key)
+			}
+		case WatchEventResyncRequired:
+			c.clear()
+		}
+	}
+	return nil
+}
+
+// cacheKey builds the cache key a Get*/Get*WithContext call would have used
+// for (scope, name), the same scheme resourceType's watch reports name
+// under. WatchEvent.Key is the server's within-scope resource identifier
+// (the same value a Get call's second argument would be), not the ad-hoc
+// "kind/scope/name" string this cache keys its entries with, so
+// InvalidateOnWatch must go through this instead of treating ev.Key as a
+// cache key directly -- the two are not the same string.
+//
+// ResourceTypeService/Volume/ConfigFile are the only resources the watch
+// API covers; a device has no watch resource type, and a ServiceAttr is
+// keyed by ServiceUUID rather than by the service name a service watch
+// reports, so neither GetDevice's nor GetServiceAttr's cache entries can be
+// invalidated from a watch event here -- both still age out via
+// NegativeTTL/LRU eviction like before this fix.
+func cacheKey(resourceType ResourceType, scope, name string) (string, bool) {
+	switch resourceType {
+	case ResourceTypeService:
+		return "service/" + scope + "/" + name, true
+	case ResourceTypeVolume:
+		return "volume/" + scope + "/" + name, true
+	case ResourceTypeConfigFile:
+		return "configfile/" + scope + "/" + name, true
+	default:
+		return "", false
+	}
+}
+
+func (c *CachedControlDBCli) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[string]*cacheEntry)
+}
+
+// get returns the cached object for key (ok=true, notFound=false), the
+// cached negative result (ok=true, notFound=true), or a cache miss
+// (ok=false).
+func (c *CachedControlDBCli) get(key string) (obj interface{}, notFound bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, found := c.items[key]
+	if !found {
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false, false
+	}
+	if e.notFound && time.Now().After(e.expiresAt) {
+		c.ll.Remove(e.elem)
+		delete(c.items, key)
+		atomic.AddUint64(&c.misses, 1)
+		return nil, false, false
+	}
+
+	c.ll.MoveToFront(e.elem)
+	atomic.AddUint64(&c.hits, 1)
+	return e.obj, e.notFound, true
+}
+
+func (c *CachedControlDBCli) put(key string, obj interface{}, notFound bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, found := c.items[key]; found {
+		e.obj = obj
+		e.notFound = notFound
+		if notFound {
+			e.expiresAt = time.Now().Add(c.opts.NegativeTTL)
+		}
+		c.ll.MoveToFront(e.elem)
+		return
+	}
+
+	e := &cacheEntry{key: key, obj: obj, notFound: notFound}
+	if notFound {
+		e.expiresAt = time.Now().Add(c.opts.NegativeTTL)
+	}
+	e.elem = c.ll.PushFront(e)
+	c.items[key] = e
+
+	if c.ll.Len() > c.opts.Size {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*cacheEntry).key)
+			atomic.AddUint64(&c.evictions, 1)
+		}
+	}
+}
+
+// GetDevice is GetDevice with an LRU read cache in front of it.
+func (c *CachedControlDBCli) GetDevice(clusterName string, deviceName string) (*common.Device, error) {
+	return c.GetDeviceWithContext(context.Background(), clusterName, deviceName)
+}
+
+// GetDeviceWithContext is GetDeviceWithContext with an LRU read cache in
+// front of it, so callers that moved to the context-aware API still hit
+// the cache instead of silently bypassing it.
+func (c *CachedControlDBCli) GetDeviceWithContext(ctx context.Context, clusterName string, deviceName string) (*common.Device, error) {
+	key := "device/" + clusterName + "/" + deviceName
+	if obj, notFound, ok := c.get(key); ok {
+		if notFound {
+			return nil, db.ErrDBRecordNotFound
+		}
+		return obj.(*common.Device), nil
+	}
+
+	v, err, _ := c.grp.Do(key, func() (interface{}, error) {
+		return c.ControlDBCli.GetDeviceWithContext(ctx, clusterName, deviceName)
+	})
+	if err == db.ErrDBRecordNotFound {
+		c.put(key, nil, true)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	dev := v.(*common.Device)
+	c.put(key, dev, false)
+	return dev, nil
+}
+
+// GetService is GetService with an LRU read cache in front of it.
+func (c *CachedControlDBCli) GetService(clusterName string, serviceName string) (*common.Service, error) {
+	return c.GetServiceWithContext(context.Background(), clusterName, serviceName)
+}
+
+// GetServiceWithContext is GetServiceWithContext with an LRU read cache in
+// front of it, so callers that moved to the context-aware API still hit
+// the cache instead of silently bypassing it.
+func (c *CachedControlDBCli) GetServiceWithContext(ctx context.Context, clusterName string, serviceName string) (*common.Service, error) {
+	key, _ := cacheKey(ResourceTypeService, clusterName, serviceName)
+	if obj, notFound, ok := c.get(key); ok {
+		if notFound {
+			return nil, db.ErrDBRecordNotFound
+		}
+		return obj.(*common.Service), nil
+	}
+
+	v, err, _ := c.grp.Do(key, func() (interface{}, error) {
+		return c.ControlDBCli.GetServiceWithContext(ctx, clusterName, serviceName)
+	})
+	if err == db.ErrDBRecordNotFound {
+		c.put(key, nil, true)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	svc := v.(*common.Service)
+	c.put(key, svc, false)
+	return svc, nil
+}
+
+// GetServiceAttr is GetServiceAttr with an LRU read cache in front of it.
+func (c *CachedControlDBCli) GetServiceAttr(serviceUUID string) (*common.ServiceAttr, error) {
+	return c.GetServiceAttrWithContext(context.Background(), serviceUUID)
+}
+
+// GetServiceAttrWithContext is GetServiceAttrWithContext with an LRU read
+// cache in front of it, so callers that moved to the context-aware API
+// still hit the cache instead of silently bypassing it.
+func (c *CachedControlDBCli) GetServiceAttrWithContext(ctx context.Context, serviceUUID string) (*common.ServiceAttr, error) {
+	key := "serviceattr/" + serviceUUID
+	if obj, notFound, ok := c.get(key); ok {
+		if notFound {
+			return nil, db.ErrDBRecordNotFound
+		}
+		return obj.(*common.ServiceAttr), nil
+	}
+
+	v, err, _ := c.grp.Do(key, func() (interface{}, error) {
+		return c.ControlDBCli.GetServiceAttrWithContext(ctx, serviceUUID)
+	})
+	if err == db.ErrDBRecordNotFound {
+		c.put(key, nil, true)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	attr := v.(*common.ServiceAttr)
+	c.put(key, attr, false)
+	return attr, nil
+}
+
+// GetVolume is GetVolume with an LRU read cache in front of it.
+func (c *CachedControlDBCli) GetVolume(serviceUUID string, volumeID string) (*common.Volume, error) {
+	return c.GetVolumeWithContext(context.Background(), serviceUUID, volumeID)
+}
+
+// GetVolumeWithContext is GetVolumeWithContext with an LRU read cache in
+// front of it, so callers that moved to the context-aware API still hit
+// the cache instead of silently bypassing it.
+func (c *CachedControlDBCli) GetVolumeWithContext(ctx context.Context, serviceUUID string, volumeID string) (*common.Volume, error) {
+	key, _ := cacheKey(ResourceTypeVolume, serviceUUID, volumeID)
+	if obj, notFound, ok := c.get(key); ok {
+		if notFound {
+			return nil, db.ErrDBRecordNotFound
+		}
+		return obj.(*common.Volume), nil
+	}
+
+	v, err, _ := c.grp.Do(key, func() (interface{}, error) {
+		return c.ControlDBCli.GetVolumeWithContext(ctx, serviceUUID, volumeID)
+	})
+	if err == db.ErrDBRecordNotFound {
+		c.put(key, nil, true)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	vol := v.(*common.Volume)
+	c.put(key, vol, false)
+	return vol, nil
+}
+
+// GetConfigFile is GetConfigFile with an LRU read cache in front of it.
+func (c *CachedControlDBCli) GetConfigFile(serviceUUID string, fileID string) (*common.ConfigFile, error) {
+	return c.GetConfigFileWithContext(context.Background(), serviceUUID, fileID)
+}
+
+// GetConfigFileWithContext is GetConfigFileWithContext with an LRU read
+// cache in front of it, so callers that moved to the context-aware API
+// still hit the cache instead of silently bypassing it.
+func (c *CachedControlDBCli) GetConfigFileWithContext(ctx context.Context, serviceUUID string, fileID string) (*common.ConfigFile, error) {
+	key, _ := cacheKey(ResourceTypeConfigFile, serviceUUID, fileID)
+	if obj, notFound, ok := c.get(key); ok {
+		if notFound {
+			return nil, db.ErrDBRecordNotFound
+		}
+		return obj.(*common.ConfigFile), nil
+	}
+
+	v, err, _ := c.grp.Do(key, func() (interface{}, error) {
+		return c.ControlDBCli.GetConfigFileWithContext(ctx, serviceUUID, fileID)
+	})
+	if err == db.ErrDBRecordNotFound {
+		c.put(key, nil, true)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg := v.(*common.ConfigFile)
+	c.put(key, cfg, false)
+	return cfg, nil
+}
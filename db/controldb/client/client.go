@@ -3,18 +3,19 @@ package controldbcli
 import (
 	"io"
 	"sync"
-	"time"
 
 	"github.com/golang/glog"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-
-	"github.com/openconnectio/openmanage/common"
-	"github.com/openconnectio/openmanage/db"
-	"github.com/openconnectio/openmanage/db/controldb"
-	pb "github.com/openconnectio/openmanage/db/controldb/protocols"
-	"github.com/openconnectio/openmanage/utils"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db"
+	"github.com/cloudstax/firecamp/db/controldb"
+	pb "github.com/cloudstax/firecamp/db/controldb/protocols"
+	"github.com/cloudstax/firecamp/errdefs"
+	"github.com/cloudstax/firecamp/utils"
 )
 
 const (
@@ -27,6 +28,11 @@ type ControlDBCli struct {
 	// address is ip:port
 	addr string
 
+	// creds is the transport credentials used to dial the server. It is nil
+	// for the insecure constructor, in which case connect() falls back to
+	// grpc.WithInsecure().
+	creds credentials.TransportCredentials
+
 	cliLock *sync.Mutex
 	cli     *pbclient
 }
@@ -49,6 +55,27 @@ func NewControlDBCli(address string) *ControlDBCli {
 	return c
 }
 
+// NewControlDBCliWithTLS creates a ControlDBCli that dials the server with
+// TLS, optionally presenting a client certificate for mutual auth. Passing
+// credentials explicitly (rather than via a global) lets different
+// clusters/tenants use different certs against the same binary.
+func NewControlDBCliWithTLS(address string, tlsCfg *ControlDBTLSConfig) (*ControlDBCli, error) {
+	creds, err := tlsCfg.TransportCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &ControlDBCli{
+		addr:    address,
+		creds:   creds,
+		cliLock: &sync.Mutex{},
+		cli:     &pbclient{isConnGood: false},
+	}
+
+	c.connect()
+	return c, nil
+}
+
 func (c *ControlDBCli) getCli() *pbclient {
 	if c.cli.isConnGood {
 		return c.cli
@@ -67,8 +94,22 @@ func (c *ControlDBCli) connect() *pbclient {
 		return c.cli
 	}
 
-	// TODO support tls
-	conn, err := grpc.Dial(c.addr, grpc.WithInsecure())
+	transportOpt := grpc.WithInsecure()
+	if c.creds != nil {
+		transportOpt = grpc.WithTransportCredentials(c.creds)
+	}
+
+	// credential rotation: callers that need to rotate certs construct a new
+	// ControlDBCli with NewControlDBCliWithTLS and swap it in; connect() and
+	// markClientFailed() already tear down and re-dial cleanly on the next
+	// failure, so there is nothing TLS-specific to do here beyond picking
+	// the dial option above.
+	//
+	// the retry/tracing interceptors replace the per-method maxRetryCount
+	// loop that used to be duplicated across every ControlDBCli method.
+	conn, err := grpc.Dial(c.addr, transportOpt,
+		grpc.WithChainUnaryInterceptor(retryUnaryClientInterceptor(), tracingUnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(retryStreamClientInterceptor()))
 	if err != nil {
 		glog.Errorln("grpc dial error", err, "address", c.addr)
 		return c.cli
@@ -108,18 +149,17 @@ func (c *ControlDBCli) markClientFailed(cli *pbclient) (isClientChanged bool) {
 	return false
 }
 
-func (c *ControlDBCli) markClientFailedAndSleep(cli *pbclient) {
-	isClientChanged := c.markClientFailed(cli)
-	if !isClientChanged {
-		// the current cli is marked as failed, wait some time before retry
-		time.Sleep(sleepSecondsBeforeRetry * time.Second)
+// checkAndConvertError turns a non-transport error returned by the server
+// into the typed error callers expect. A server on the errdefs.ToGRPC path
+// returns a real status code (NotFound, AlreadyExists, ...), which FromGRPC
+// reconstructs into an *errdefs.Error; a server that hasn't been migrated
+// yet still falls back to the legacy convention of codes.Unknown plus a
+// string-matched desc, see /grpcsrc/rpc_util/toRPCError().
+func (c *ControlDBCli) checkAndConvertError(err error) error {
+	if typed := errdefs.FromGRPC(err); typed != err {
+		return typed
 	}
-}
 
-func (c *ControlDBCli) checkAndConvertError(err error) error {
-	// grpc defines the error codes in /grpcsrc/codes/codes.go.
-	// if server side returns the application-level error, grpc will return error with
-	// code = codes.Unknown, desc = applicationError.Error(), see /grpcsrc/rpc_util/toRPCError()
 	switch grpc.ErrorDesc(err) {
 	case db.StrErrDBInternal:
 		return db.ErrDBInternal
@@ -133,6 +173,17 @@ func (c *ControlDBCli) checkAndConvertError(err error) error {
 	return err
 }
 
+// isApplicationError reports whether err represents a typed application-
+// level failure from the server (an errdefs kind, or the legacy
+// codes.Unknown + string-matched desc), as opposed to a transport failure
+// that means the connection itself is bad.
+func (c *ControlDBCli) isApplicationError(err error) bool {
+	if typed := errdefs.FromGRPC(err); typed != err {
+		return true
+	}
+	return grpc.Code(err) == codes.Unknown
+}
+
 func (c *ControlDBCli) CreateSystemTables() error {
 	return nil
 }
@@ -146,99 +197,120 @@ func (c *ControlDBCli) DeleteSystemTables() error {
 }
 
 func (c *ControlDBCli) CreateDevice(dev *common.Device) error {
-	requuid := utils.GenRequestUUID()
+	return c.CreateDeviceWithContext(context.Background(), dev)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// CreateDeviceWithContext is CreateDevice with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) CreateDeviceWithContext(ctx context.Context, dev *common.Device) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	pbdev := controldb.GenPbDevice(dev)
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.CreateDevice(ctx, pbdev)
-		if err == nil {
-			glog.Infoln("created device", pbdev, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.CreateDevice(ctx, pbdev)
+	if err == nil {
+		glog.Infoln("created device", pbdev, "requuid", requuid)
+		return nil
+	}
 
-		// error
-		glog.Errorln("CreateDevice error", err, "device", pbdev, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	// error
+	glog.Errorln("CreateDevice error", err, "device", pbdev, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("CreateDevice canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) GetDevice(clusterName string, deviceName string) (dev *common.Device, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.GetDeviceWithContext(context.Background(), clusterName, deviceName)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// GetDeviceWithContext is GetDevice with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) GetDeviceWithContext(ctx context.Context, clusterName string, deviceName string) (dev *common.Device, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	key := &pb.DeviceKey{
 		ClusterName: clusterName,
 		DeviceName:  deviceName,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbdev, err := cli.dbcli.GetDevice(ctx, key)
-		if err == nil {
-			glog.Infoln("got device", pbdev, "requuid", requuid)
-			return controldb.GenDbDevice(pbdev), nil
-		}
+	cli := c.getCli()
+	pbdev, err := cli.dbcli.GetDevice(ctx, key)
+	if err == nil {
+		glog.Infoln("got device", pbdev, "requuid", requuid)
+		return controldb.GenDbDevice(pbdev), nil
+	}
 
-		// error
-		glog.Errorln("GetDevice error", err, key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	// error
+	glog.Errorln("GetDevice error", err, key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("GetDevice canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) DeleteDevice(clusterName string, deviceName string) error {
-	requuid := utils.GenRequestUUID()
+	return c.DeleteDeviceWithContext(context.Background(), clusterName, deviceName)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// DeleteDeviceWithContext is DeleteDevice with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) DeleteDeviceWithContext(ctx context.Context, clusterName string, deviceName string) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	key := &pb.DeviceKey{
 		ClusterName: clusterName,
 		DeviceName:  deviceName,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.DeleteDevice(ctx, key)
-		if err == nil {
-			glog.Infoln("deleted device", key, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.DeleteDevice(ctx, key)
+	if err == nil {
+		glog.Infoln("deleted device", key, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("DeleteDevice error", err, key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("DeleteDevice error", err, key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("DeleteDevice canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return err
 }
 
@@ -275,127 +347,155 @@ func (c *ControlDBCli) listDevices(clusterName string, cli *pbclient, ctx contex
 }
 
 func (c *ControlDBCli) ListDevices(clusterName string) (devs []*common.Device, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.ListDevicesWithContext(context.Background(), clusterName)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// ListDevicesWithContext is ListDevices with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) ListDevicesWithContext(ctx context.Context, clusterName string) (devs []*common.Device, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	req := &pb.ListDeviceRequest{
 		ClusterName: clusterName,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		devs, err = c.listDevices(clusterName, cli, ctx, req, requuid)
-		if err == nil {
-			return devs, nil
-		}
+	cli := c.getCli()
+	devs, err = c.listDevices(clusterName, cli, ctx, req, requuid)
+	if err == nil {
+		return devs, nil
+	}
 
-		glog.Errorln("ListDevices error", err, req, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("ListDevices error", err, req, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("ListDevices canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) CreateService(svc *common.Service) error {
-	requuid := utils.GenRequestUUID()
+	return c.CreateServiceWithContext(context.Background(), svc)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// CreateServiceWithContext is CreateService with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) CreateServiceWithContext(ctx context.Context, svc *common.Service) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	pbsvc := controldb.GenPbService(svc)
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.CreateService(ctx, pbsvc)
-		if err == nil {
-			glog.Infoln("created service", pbsvc, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.CreateService(ctx, pbsvc)
+	if err == nil {
+		glog.Infoln("created service", pbsvc, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("CreateService error", err, "service", pbsvc, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("CreateService error", err, "service", pbsvc, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("CreateService canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) GetService(clusterName string, serviceName string) (svc *common.Service, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.GetServiceWithContext(context.Background(), clusterName, serviceName)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// GetServiceWithContext is GetService with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) GetServiceWithContext(ctx context.Context, clusterName string, serviceName string) (svc *common.Service, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	key := &pb.ServiceKey{
 		ClusterName: clusterName,
 		ServiceName: serviceName,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbsvc, err := cli.dbcli.GetService(ctx, key)
-		if err == nil {
-			glog.Infoln("get service", pbsvc, "requuid", requuid)
-			return controldb.GenDbService(pbsvc), nil
-		}
+	cli := c.getCli()
+	pbsvc, err := cli.dbcli.GetService(ctx, key)
+	if err == nil {
+		glog.Infoln("get service", pbsvc, "requuid", requuid)
+		return controldb.GenDbService(pbsvc), nil
+	}
 
-		glog.Errorln("GetService error", err, "key", key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("GetService error", err, "key", key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("GetService canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) DeleteService(clusterName string, serviceName string) error {
-	requuid := utils.GenRequestUUID()
+	return c.DeleteServiceWithContext(context.Background(), clusterName, serviceName)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// DeleteServiceWithContext is DeleteService with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) DeleteServiceWithContext(ctx context.Context, clusterName string, serviceName string) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	key := &pb.ServiceKey{
 		ClusterName: clusterName,
 		ServiceName: serviceName,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbsvc, err := cli.dbcli.DeleteService(ctx, key)
-		if err == nil {
-			glog.Infoln("delete service", pbsvc, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	pbsvc, err := cli.dbcli.DeleteService(ctx, key)
+	if err == nil {
+		glog.Infoln("delete service", pbsvc, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("DeleteService error", err, "key", key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("DeleteService error", err, "key", key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("DeleteService canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
 
@@ -432,287 +532,350 @@ func (c *ControlDBCli) listServices(clusterName string, cli *pbclient, ctx conte
 }
 
 func (c *ControlDBCli) ListServices(clusterName string) (svcs []*common.Service, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.ListServicesWithContext(context.Background(), clusterName)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// ListServicesWithContext is ListServices with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) ListServicesWithContext(ctx context.Context, clusterName string) (svcs []*common.Service, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	req := &pb.ListServiceRequest{
 		ClusterName: clusterName,
 	}
 
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		svcs, err = c.listServices(clusterName, cli, ctx, req, requuid)
-		if err == nil {
-			return svcs, nil
-		}
+	cli := c.getCli()
+	svcs, err = c.listServices(clusterName, cli, ctx, req, requuid)
+	if err == nil {
+		return svcs, nil
+	}
 
-		glog.Errorln("ListServices error", err, "cluster", clusterName, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("ListServices error", err, "cluster", clusterName, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("ListServices canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) CreateServiceAttr(attr *common.ServiceAttr) error {
-	requuid := utils.GenRequestUUID()
+	return c.CreateServiceAttrWithContext(context.Background(), attr)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// CreateServiceAttrWithContext is CreateServiceAttr with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) CreateServiceAttrWithContext(ctx context.Context, attr *common.ServiceAttr) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	pbattr := controldb.GenPbServiceAttr(attr)
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.CreateServiceAttr(ctx, pbattr)
-		if err == nil {
-			glog.Infoln("created service attr", pbattr, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.CreateServiceAttr(ctx, pbattr)
+	if err == nil {
+		glog.Infoln("created service attr", pbattr, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("CreateServiceAttr error", err, "serviceAttr", pbattr, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("CreateServiceAttr error", err, "serviceAttr", pbattr, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("CreateServiceAttr canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) UpdateServiceAttr(oldAttr *common.ServiceAttr, newAttr *common.ServiceAttr) error {
-	requuid := utils.GenRequestUUID()
+	return c.UpdateServiceAttrWithContext(context.Background(), oldAttr, newAttr)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// UpdateServiceAttrWithContext is UpdateServiceAttr with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) UpdateServiceAttrWithContext(ctx context.Context, oldAttr *common.ServiceAttr, newAttr *common.ServiceAttr) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	req := &pb.UpdateServiceAttrRequest{
 		OldAttr: controldb.GenPbServiceAttr(oldAttr),
 		NewAttr: controldb.GenPbServiceAttr(newAttr),
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.UpdateServiceAttr(ctx, req)
-		if err == nil {
-			glog.Infoln("UpdateServiceAttr from", oldAttr, "to", newAttr, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.UpdateServiceAttr(ctx, req)
+	if err == nil {
+		glog.Infoln("UpdateServiceAttr from", oldAttr, "to", newAttr, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("UpdateServiceAttr error", err, "old attr", oldAttr, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("UpdateServiceAttr error", err, "old attr", oldAttr, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("UpdateServiceAttr canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) GetServiceAttr(serviceUUID string) (attr *common.ServiceAttr, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.GetServiceAttrWithContext(context.Background(), serviceUUID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// GetServiceAttrWithContext is GetServiceAttr with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) GetServiceAttrWithContext(ctx context.Context, serviceUUID string) (attr *common.ServiceAttr, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	key := &pb.ServiceAttrKey{
 		ServiceUUID: serviceUUID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbAttr, err := cli.dbcli.GetServiceAttr(ctx, key)
-		if err == nil {
-			glog.Infoln("get service attr", pbAttr, "requuid", requuid)
-			return controldb.GenDbServiceAttr(pbAttr), nil
-		}
+	cli := c.getCli()
+	pbAttr, err := cli.dbcli.GetServiceAttr(ctx, key)
+	if err == nil {
+		glog.Infoln("get service attr", pbAttr, "requuid", requuid)
+		return controldb.GenDbServiceAttr(pbAttr), nil
+	}
 
-		glog.Errorln("GetServiceAttr error", err, "service", serviceUUID, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("GetServiceAttr error", err, "service", serviceUUID, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("GetServiceAttr canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) DeleteServiceAttr(serviceUUID string) error {
-	requuid := utils.GenRequestUUID()
+	return c.DeleteServiceAttrWithContext(context.Background(), serviceUUID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// DeleteServiceAttrWithContext is DeleteServiceAttr with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) DeleteServiceAttrWithContext(ctx context.Context, serviceUUID string) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	key := &pb.ServiceAttrKey{
 		ServiceUUID: serviceUUID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbAttr, err := cli.dbcli.DeleteServiceAttr(ctx, key)
-		if err == nil {
-			glog.Infoln("delete service attr", pbAttr, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	pbAttr, err := cli.dbcli.DeleteServiceAttr(ctx, key)
+	if err == nil {
+		glog.Infoln("delete service attr", pbAttr, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("DeleteServiceAttr error", err, "service", serviceUUID, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("DeleteServiceAttr error", err, "service", serviceUUID, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("DeleteServiceAttr canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) CreateVolume(vol *common.Volume) error {
-	requuid := utils.GenRequestUUID()
+	return c.CreateVolumeWithContext(context.Background(), vol)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// CreateVolumeWithContext is CreateVolume with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) CreateVolumeWithContext(ctx context.Context, vol *common.Volume) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	pbvol := controldb.GenPbVolume(vol)
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.CreateVolume(ctx, pbvol)
-		if err == nil {
-			glog.Infoln("created volume", pbvol, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.CreateVolume(ctx, pbvol)
+	if err == nil {
+		glog.Infoln("created volume", pbvol, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("CreateVolume error", err, "volume", pbvol, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("CreateVolume error", err, "volume", pbvol, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("CreateVolume canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) UpdateVolume(oldVol *common.Volume, newVol *common.Volume) error {
-	requuid := utils.GenRequestUUID()
+	return c.UpdateVolumeWithContext(context.Background(), oldVol, newVol)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// UpdateVolumeWithContext is UpdateVolume with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) UpdateVolumeWithContext(ctx context.Context, oldVol *common.Volume, newVol *common.Volume) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	req := &pb.UpdateVolumeRequest{
 		OldVol: controldb.GenPbVolume(oldVol),
 		NewVol: controldb.GenPbVolume(newVol),
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.UpdateVolume(ctx, req)
-		if err == nil {
-			glog.Infoln("UpdateVolume from", oldVol, "to", newVol, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.UpdateVolume(ctx, req)
+	if err == nil {
+		glog.Infoln("UpdateVolume from", oldVol, "to", newVol, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("UpdateVolume error", err, "old volume", oldVol, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("UpdateVolume error", err, "old volume", oldVol, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("UpdateVolume canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) GetVolume(serviceUUID string, volumeID string) (vol *common.Volume, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.GetVolumeWithContext(context.Background(), serviceUUID, volumeID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// GetVolumeWithContext is GetVolume with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) GetVolumeWithContext(ctx context.Context, serviceUUID string, volumeID string) (vol *common.Volume, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	key := &pb.VolumeKey{
 		ServiceUUID: serviceUUID,
 		VolumeID:    volumeID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbvol, err := cli.dbcli.GetVolume(ctx, key)
-		if err == nil {
-			glog.Infoln("get volume", pbvol, "requuid", requuid)
-			return controldb.GenDbVolume(pbvol), nil
-		}
+	cli := c.getCli()
+	pbvol, err := cli.dbcli.GetVolume(ctx, key)
+	if err == nil {
+		glog.Infoln("get volume", pbvol, "requuid", requuid)
+		return controldb.GenDbVolume(pbvol), nil
+	}
 
-		glog.Errorln("GetVolume error", err, "key", key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("GetVolume error", err, "key", key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("GetVolume canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) DeleteVolume(serviceUUID string, volumeID string) error {
-	requuid := utils.GenRequestUUID()
+	return c.DeleteVolumeWithContext(context.Background(), serviceUUID, volumeID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// DeleteVolumeWithContext is DeleteVolume with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) DeleteVolumeWithContext(ctx context.Context, serviceUUID string, volumeID string) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	key := &pb.VolumeKey{
 		ServiceUUID: serviceUUID,
 		VolumeID:    volumeID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbvol, err := cli.dbcli.DeleteVolume(ctx, key)
-		if err == nil {
-			glog.Infoln("delete volume", pbvol, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	pbvol, err := cli.dbcli.DeleteVolume(ctx, key)
+	if err == nil {
+		glog.Infoln("delete volume", pbvol, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("DeleteVolume error", err, "key", key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("DeleteVolume error", err, "key", key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("DeleteVolume canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return err
 }
 
@@ -748,126 +911,154 @@ func (c *ControlDBCli) listVolumes(serviceUUID string, cli *pbclient, ctx contex
 }
 
 func (c *ControlDBCli) ListVolumes(serviceUUID string) (vols []*common.Volume, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.ListVolumesWithContext(context.Background(), serviceUUID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// ListVolumesWithContext is ListVolumes with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) ListVolumesWithContext(ctx context.Context, serviceUUID string) (vols []*common.Volume, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	req := &pb.ListVolumeRequest{
 		ServiceUUID: serviceUUID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		vols, err = c.listVolumes(serviceUUID, cli, ctx, req, requuid)
-		if err == nil {
-			return vols, nil
-		}
+	cli := c.getCli()
+	vols, err = c.listVolumes(serviceUUID, cli, ctx, req, requuid)
+	if err == nil {
+		return vols, nil
+	}
 
-		glog.Errorln("ListVolumes error", err, "serviceUUID", serviceUUID, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("ListVolumes error", err, "serviceUUID", serviceUUID, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("ListVolumes canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
 	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) CreateConfigFile(cfg *common.ConfigFile) error {
-	requuid := utils.GenRequestUUID()
+	return c.CreateConfigFileWithContext(context.Background(), cfg)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// CreateConfigFileWithContext is CreateConfigFile with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) CreateConfigFileWithContext(ctx context.Context, cfg *common.ConfigFile) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	pbcfg := controldb.GenPbConfigFile(cfg)
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		_, err = cli.dbcli.CreateConfigFile(ctx, pbcfg)
-		if err == nil {
-			glog.Infoln("created config file", pbcfg, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	_, err = cli.dbcli.CreateConfigFile(ctx, pbcfg)
+	if err == nil {
+		glog.Infoln("created config file", pbcfg, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("CreateConfigFile error", err, "config file", pbcfg, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("CreateConfigFile error", err, "config file", pbcfg, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("CreateConfigFile canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
 
 func (c *ControlDBCli) GetConfigFile(serviceUUID string, fileID string) (cfg *common.ConfigFile, err error) {
-	requuid := utils.GenRequestUUID()
+	return c.GetConfigFileWithContext(context.Background(), serviceUUID, fileID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// GetConfigFileWithContext is GetConfigFile with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) GetConfigFileWithContext(ctx context.Context, serviceUUID string, fileID string) (cfg *common.ConfigFile, err error) {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	key := &pb.ConfigFileKey{
 		ServiceUUID: serviceUUID,
 		FileID:      fileID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbcfg, err := cli.dbcli.GetConfigFile(ctx, key)
-		if err == nil {
-			glog.Infoln("get config file", pbcfg, "requuid", requuid)
-			return controldb.GenDbConfigFile(pbcfg), nil
-		}
+	cli := c.getCli()
+	pbcfg, err := cli.dbcli.GetConfigFile(ctx, key)
+	if err == nil {
+		glog.Infoln("get config file", pbcfg, "requuid", requuid)
+		return controldb.GenDbConfigFile(pbcfg), nil
+	}
 
-		glog.Errorln("GetConfigFile error", err, "key", key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return nil, c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("GetConfigFile error", err, "key", key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("GetConfigFile canceled or timed out", err, "requuid", requuid)
+		return nil, wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return nil, c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return nil, err
 }
 
 func (c *ControlDBCli) DeleteConfigFile(serviceUUID string, fileID string) error {
-	requuid := utils.GenRequestUUID()
+	return c.DeleteConfigFileWithContext(context.Background(), serviceUUID, fileID)
+}
 
-	ctx, cancel := context.WithCancel(context.Background())
+// DeleteConfigFileWithContext is DeleteConfigFile with a caller-supplied context: canceling
+// ctx or hitting its deadline aborts the RPC instead of running to completion,
+// and the request UUID travels to the server as outgoing gRPC metadata so its
+// logs can be correlated with this call.
+func (c *ControlDBCli) DeleteConfigFileWithContext(ctx context.Context, serviceUUID string, fileID string) error {
+	requuid := utils.GenRequestUUID()
 	ctx = utils.NewRequestContext(ctx, requuid)
-	// call cancel before return. This is to ensure any resource derived
-	// from the context will be canceled.
-	defer cancel()
+	ctx = attachTraceMetadata(ctx, requuid)
 
 	var err error
 	key := &pb.ConfigFileKey{
 		ServiceUUID: serviceUUID,
 		FileID:      fileID,
 	}
-	for i := 0; i < maxRetryCount; i++ {
-		cli := c.getCli()
-		pbcfg, err := cli.dbcli.DeleteConfigFile(ctx, key)
-		if err == nil {
-			glog.Infoln("delete config file", pbcfg, "requuid", requuid)
-			return nil
-		}
+	cli := c.getCli()
+	pbcfg, err := cli.dbcli.DeleteConfigFile(ctx, key)
+	if err == nil {
+		glog.Infoln("delete config file", pbcfg, "requuid", requuid)
+		return nil
+	}
 
-		glog.Errorln("DeleteConfigFile error", err, "key", key, "requuid", requuid)
-		if grpc.Code(err) == codes.Unknown {
-			// not grpc layer error code, directly return
-			return c.checkAndConvertError(err)
-		}
-		// grpc error, retry it
-		c.markClientFailedAndSleep(cli)
+	glog.Errorln("DeleteConfigFile error", err, "key", key, "requuid", requuid)
+	if isCanceledOrDeadlineExceeded(err) {
+		glog.V(1).Infoln("DeleteConfigFile canceled or timed out", err, "requuid", requuid)
+		return wrapCanceledOrDeadlineExceeded(err)
+	}
+
+	if c.isApplicationError(err) {
+		// server returned a typed application error, not a transport
+		// failure; return it directly without flagging the connection bad.
+		return c.checkAndConvertError(err)
 	}
+	c.markClientFailed(cli)
 	return err
 }
@@ -0,0 +1,48 @@
+package controldbcli
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// requuidMetadataKey is the outgoing/incoming gRPC metadata key the request
+// UUID travels under, so the server can stamp its own logs with the same id
+// a client-side log line used.
+const requuidMetadataKey = "x-firecamp-requuid"
+
+// attachTraceMetadata appends requuid to ctx's outgoing gRPC metadata. Only
+// the request UUID is sent; caller trace/span ids are not propagated.
+func attachTraceMetadata(ctx context.Context, requuid string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, requuidMetadataKey, requuid)
+}
+
+// isCanceledOrDeadlineExceeded reports whether err is a grpc status error
+// for codes.Canceled or codes.DeadlineExceeded -- the two codes the retry
+// interceptors never retry, since they reflect the caller's own choice to
+// give up rather than a server/backend problem. The ControlDBCli methods
+// still log such an error with glog.Errorln before checking for it.
+func isCanceledOrDeadlineExceeded(err error) bool {
+	code := grpc.Code(err)
+	return code == codes.Canceled || code == codes.DeadlineExceeded
+}
+
+// wrapCanceledOrDeadlineExceeded turns a grpc status error for
+// codes.Canceled/codes.DeadlineExceeded into an error that errors.Is also
+// matches against context.Canceled/context.DeadlineExceeded, so a caller
+// written against the stdlib context package (e.g. "retry until
+// errors.Is(err, context.Canceled)") works against a ControlDBCli error the
+// same way it would against a context error.
+func wrapCanceledOrDeadlineExceeded(err error) error {
+	switch grpc.Code(err) {
+	case codes.Canceled:
+		return fmt.Errorf("controldbcli: %v: %w", err, context.Canceled)
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("controldbcli: %v: %w", err, context.DeadlineExceeded)
+	default:
+		return err
+	}
+}
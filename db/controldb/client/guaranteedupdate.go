@@ -0,0 +1,102 @@
+// Package controldbcli's GuaranteedUpdateServiceAttr/GuaranteedUpdateVolume
+// below are NOT the revision-based optimistic concurrency this request
+// (brucejang711/firecamp#chunk2-3) asked for, and should not be read as
+// closing it. The request wants common.ServiceAttr/Volume/ConfigFile to
+// carry a ResourceVersion field, UpdateServiceAttrRequest/UpdateVolumeRequest
+// to carry an ExpectedRevision, and the server to CAS on that single int64
+// instead of comparing the full old/new struct. common.ServiceAttr,
+// common.Volume and common.ConfigFile (and the pb Update*Request messages in
+// db/controldb/protocols) are referenced throughout this package but are not
+// defined anywhere in it, so there is no struct in this tree to add
+// ResourceVersion to. GuaranteedUpdateServiceAttr/GuaranteedUpdateVolume
+// below only wrap the pre-existing full-value CAS that UpdateServiceAttr/
+// UpdateVolume already do - the exact copy-paste-per-call-site pattern a
+// ResourceVersion field was meant to replace - as the closest approximation
+// available here. This request stays open until ServiceAttr/Volume/
+// ConfigFile exist in this tree with a ResourceVersion field to build on.
+package controldbcli
+
+import (
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db"
+	"github.com/cloudstax/firecamp/utils"
+
+	"github.com/golang/glog"
+)
+
+// maxGuaranteedUpdateRetries bounds the read-modify-write retry loop below,
+// so a mutator that keeps losing the race (or a mutator with a bug that
+// always conflicts) fails loudly instead of spinning forever.
+const maxGuaranteedUpdateRetries = 10
+
+// GuaranteedUpdateServiceAttr reads the current ServiceAttr for serviceUUID,
+// applies tryUpdate to it, and submits the result with UpdateServiceAttr.
+// If the submit loses the CAS race (db.ErrDBConditionalCheckFailed, because
+// another writer updated the attr first), it re-reads the now-current attr
+// and retries tryUpdate against it, the same "read, apply, try, retry on
+// conflict" shape as the etcd/Kubernetes storage GuaranteedUpdate helper.
+// It lets callers write read-modify-write logic, e.g. "flip the service
+// status to ACTIVE", without hand-copying the CAS retry loop at every call
+// site. See the package doc comment: this compares the full ServiceAttr
+// rather than a ResourceVersion, since this tree has no ServiceAttr struct
+// to add that field to.
+func (c *ControlDBCli) GuaranteedUpdateServiceAttr(serviceUUID string, tryUpdate func(current *common.ServiceAttr) (*common.ServiceAttr, error)) (*common.ServiceAttr, error) {
+	requuid := utils.GenRequestUUID()
+
+	for i := 0; i < maxGuaranteedUpdateRetries; i++ {
+		current, err := c.GetServiceAttr(serviceUUID)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := tryUpdate(current)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.UpdateServiceAttr(current, updated)
+		if err == nil {
+			return updated, nil
+		}
+		if err != db.ErrDBConditionalCheckFailed {
+			return nil, err
+		}
+
+		glog.V(1).Infoln("GuaranteedUpdateServiceAttr lost the CAS race, retrying",
+			"service", serviceUUID, "attempt", i, "requuid", requuid)
+	}
+
+	return nil, db.ErrDBConditionalCheckFailed
+}
+
+// GuaranteedUpdateVolume is GuaranteedUpdateServiceAttr's counterpart for
+// Volume rows, keyed by serviceUUID/volumeID. Same full-value CAS caveat as
+// GuaranteedUpdateServiceAttr applies here.
+func (c *ControlDBCli) GuaranteedUpdateVolume(serviceUUID string, volumeID string, tryUpdate func(current *common.Volume) (*common.Volume, error)) (*common.Volume, error) {
+	requuid := utils.GenRequestUUID()
+
+	for i := 0; i < maxGuaranteedUpdateRetries; i++ {
+		current, err := c.GetVolume(serviceUUID, volumeID)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := tryUpdate(current)
+		if err != nil {
+			return nil, err
+		}
+
+		err = c.UpdateVolume(current, updated)
+		if err == nil {
+			return updated, nil
+		}
+		if err != db.ErrDBConditionalCheckFailed {
+			return nil, err
+		}
+
+		glog.V(1).Infoln("GuaranteedUpdateVolume lost the CAS race, retrying",
+			"service", serviceUUID, "volume", volumeID, "attempt", i, "requuid", requuid)
+	}
+
+	return nil, db.ErrDBConditionalCheckFailed
+}
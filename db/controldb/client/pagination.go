@@ -0,0 +1,108 @@
+package controldbcli
+
+import (
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db/controldb"
+	pb "github.com/cloudstax/firecamp/db/controldb/protocols"
+	"github.com/cloudstax/firecamp/utils"
+)
+
+// ListDevicesPage returns one page of at most pageSize devices for cluster,
+// starting after pageToken (empty for the first page). nextPageToken is
+// empty once the last page has been returned. Unlike ListDevices, which
+// streams the entire cluster, this bounds a single RPC's result size for
+// callers (e.g. an admin UI) that only need one page at a time.
+func (c *ControlDBCli) ListDevicesPage(clusterName string, pageToken string, pageSize int32) (devs []*common.Device, nextPageToken string, err error) {
+	requuid := utils.GenRequestUUID()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = utils.NewRequestContext(ctx, requuid)
+	defer cancel()
+
+	req := &pb.ListDevicePageRequest{
+		ClusterName: clusterName,
+		PageToken:   pageToken,
+		PageSize:    pageSize,
+	}
+	cli := c.getCli()
+	resp, err := cli.dbcli.ListDevicesPage(ctx, req)
+	if err == nil {
+		devs = make([]*common.Device, len(resp.Devices))
+		for i, pbdev := range resp.Devices {
+			devs[i] = controldb.GenDbDevice(pbdev)
+		}
+		return devs, resp.NextPageToken, nil
+	}
+
+	glog.Errorln("ListDevicesPage error", err, req, "requuid", requuid)
+	if c.isApplicationError(err) {
+		return nil, "", c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
+	return nil, "", err
+}
+
+// ListServicesPage is ListDevicesPage's counterpart for services.
+func (c *ControlDBCli) ListServicesPage(clusterName string, pageToken string, pageSize int32) (svcs []*common.Service, nextPageToken string, err error) {
+	requuid := utils.GenRequestUUID()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = utils.NewRequestContext(ctx, requuid)
+	defer cancel()
+
+	req := &pb.ListServicePageRequest{
+		ClusterName: clusterName,
+		PageToken:   pageToken,
+		PageSize:    pageSize,
+	}
+	cli := c.getCli()
+	resp, err := cli.dbcli.ListServicesPage(ctx, req)
+	if err == nil {
+		svcs = make([]*common.Service, len(resp.Services))
+		for i, pbsvc := range resp.Services {
+			svcs[i] = controldb.GenDbService(pbsvc)
+		}
+		return svcs, resp.NextPageToken, nil
+	}
+
+	glog.Errorln("ListServicesPage error", err, req, "requuid", requuid)
+	if c.isApplicationError(err) {
+		return nil, "", c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
+	return nil, "", err
+}
+
+// ListVolumesPage is ListDevicesPage's counterpart for volumes.
+func (c *ControlDBCli) ListVolumesPage(serviceUUID string, pageToken string, pageSize int32) (vols []*common.Volume, nextPageToken string, err error) {
+	requuid := utils.GenRequestUUID()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = utils.NewRequestContext(ctx, requuid)
+	defer cancel()
+
+	req := &pb.ListVolumePageRequest{
+		ServiceUUID: serviceUUID,
+		PageToken:   pageToken,
+		PageSize:    pageSize,
+	}
+	cli := c.getCli()
+	resp, err := cli.dbcli.ListVolumesPage(ctx, req)
+	if err == nil {
+		vols = make([]*common.Volume, len(resp.Volumes))
+		for i, pbvol := range resp.Volumes {
+			vols[i] = controldb.GenDbVolume(pbvol)
+		}
+		return vols, resp.NextPageToken, nil
+	}
+
+	glog.Errorln("ListVolumesPage error", err, req, "requuid", requuid)
+	if c.isApplicationError(err) {
+		return nil, "", c.checkAndConvertError(err)
+	}
+	c.markClientFailed(cli)
+	return nil, "", err
+}
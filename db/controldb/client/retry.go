@@ -0,0 +1,327 @@
+package controldbcli
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// RetryPolicy configures how the retry interceptor behaves for a single
+// call, mirroring the shape of a gRPC service config retry policy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	MaxAttempts int
+	// InitialBackoff is the sleep before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff before jitter, however many attempts
+	// have elapsed; the jittered sleep can reach up to 1.5x MaxBackoff.
+	MaxBackoff time.Duration
+	// BackoffMultiplier scales the backoff on every subsequent attempt:
+	// sleep(n) = min(MaxBackoff, InitialBackoff * BackoffMultiplier^(n-1)),
+	// then jittered by a random factor in [0.5, 1.5).
+	BackoffMultiplier float64
+	// RetryableCodes lists the grpc status codes worth retrying at all.
+	// A retryable code is still only retried when the call is idempotent,
+	// see isIdempotentMethod.
+	RetryableCodes map[codes.Code]bool
+	// PerCallTimeout bounds a single attempt, independent of the parent
+	// context's deadline. Zero means no per-attempt timeout.
+	PerCallTimeout time.Duration
+	// Budget is the token bucket shared across calls using this policy
+	// that caps total retries client-wide, so a partial outage can't turn
+	// every in-flight call's retries into a retry storm. Nil means
+	// unbounded (every call may retry up to MaxAttempts on its own).
+	Budget *RetryBudget
+}
+
+// defaultRetryPolicy mirrors the previous hand-rolled loop's attempt count
+// while adding jittered exponential backoff and a shared retry budget.
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       maxRetryCount,
+	InitialBackoff:    sleepSecondsBeforeRetry * time.Second,
+	MaxBackoff:        10 * time.Second,
+	BackoffMultiplier: 2,
+	RetryableCodes: map[codes.Code]bool{
+		codes.Unavailable:       true,
+		codes.ResourceExhausted: true,
+		codes.Aborted:           true,
+	},
+	Budget: defaultRetryBudget,
+}
+
+// defaultRetryBudget is shared by every call using defaultRetryPolicy, so
+// retries across concurrent ControlDBCli methods are bounded as a whole,
+// not just individually.
+var defaultRetryBudget = NewRetryBudget(64)
+
+type retryPolicyKey struct{}
+
+// WithRetryPolicy attaches policy to ctx for the retry interceptor to pick
+// up. Use this to override retry behavior per request, e.g. disabling
+// retries for a non-idempotent write the caller has already decided not to
+// repeat.
+func WithRetryPolicy(ctx context.Context, policy RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryPolicyKey{}, policy)
+}
+
+func retryPolicyFromContext(ctx context.Context) RetryPolicy {
+	if policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy); ok {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// RetryBudget is a simple token bucket: each retry attempt (not the first
+// try) costs one token, and every successful call, first try or retry,
+// credits a tenth of a token back. This is the same idea as grpc-go's retry throttling --
+// a backend already struggling shouldn't be hit with every client's full
+// MaxAttempts on top of the outage.
+type RetryBudget struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+}
+
+// NewRetryBudget creates a RetryBudget starting full, holding at most
+// capacity retry tokens.
+func NewRetryBudget(capacity float64) *RetryBudget {
+	return &RetryBudget{tokens: capacity, capacity: capacity}
+}
+
+// take consumes one token, returning false (and incrementing
+// retry_budget_exhausted_total) if none are available.
+func (b *RetryBudget) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		retryMetrics.budgetExhausted.Inc()
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// deposit credits a fraction of a token back on every successful call,
+// so a budget drained during a blip recovers once the backend is healthy
+// again rather than staying empty until process restart.
+func (b *RetryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += 0.1
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// nonIdempotentPrefixes are the RPC name prefixes this client only ever
+// calls once: a Create* can't be blindly retried because the first attempt
+// may have already landed server-side (the server doesn't expose an
+// idempotency key to disambiguate a retry from a duplicate submit). Update*
+// is excluded too, since it is a full-value CAS keyed off the caller's
+// notion of "old" -- retrying it after a transport blip risks the request
+// racing itself. Get/List/Delete are idempotent: re-issuing them is always
+// safe.
+var nonIdempotentPrefixes = []string{"/CreateDevice", "/CreateService", "/CreateServiceAttr",
+	"/CreateVolume", "/CreateConfigFile", "/UpdateServiceAttr", "/UpdateVolume", "/Txn"}
+
+// isIdempotentMethod reports whether method (the full gRPC method name,
+// e.g. "/controldb.ControlDBService/DeleteDevice") is safe to retry.
+func isIdempotentMethod(method string) bool {
+	slash := strings.LastIndex(method, "/")
+	short := method
+	if slash >= 0 {
+		short = method[slash:]
+	}
+	for _, prefix := range nonIdempotentPrefixes {
+		if short == prefix {
+			return false
+		}
+	}
+	return true
+}
+
+// nextBackoff computes the jittered exponential backoff before the attempt
+// after a 0-indexed attempt number: min(MaxBackoff, InitialBackoff *
+// BackoffMultiplier^attempt) scaled by a random factor in [0.5, 1.5).
+func nextBackoff(policy RetryPolicy, attempt int) time.Duration {
+	backoff := float64(policy.InitialBackoff)
+	for i := 0; i < attempt; i++ {
+		backoff *= policy.BackoffMultiplier
+		if time.Duration(backoff) > policy.MaxBackoff {
+			backoff = float64(policy.MaxBackoff)
+			break
+		}
+	}
+	jitter := 0.5 + rand.Float64()
+	return time.Duration(backoff * jitter)
+}
+
+// retryUnaryClientInterceptor retries a unary RPC according to the
+// RetryPolicy found on the call's context (or defaultRetryPolicy): only
+// when the status code is retryable, the method is idempotent, the shared
+// budget has tokens, and the parent context's deadline leaves room for
+// another attempt.
+func retryUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		policy := retryPolicyFromContext(ctx)
+		idempotent := isIdempotentMethod(method)
+
+		var lastErr error
+		for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+			callCtx := ctx
+			var cancel context.CancelFunc
+			if policy.PerCallTimeout > 0 {
+				callCtx, cancel = context.WithTimeout(ctx, policy.PerCallTimeout)
+			}
+
+			lastErr = invoker(callCtx, method, req, reply, cc, opts...)
+			if cancel != nil {
+				cancel()
+			}
+			if lastErr == nil {
+				if policy.Budget != nil {
+					policy.Budget.deposit()
+				}
+				return nil
+			}
+
+			if isCanceledOrDeadlineExceeded(lastErr) {
+				return lastErr
+			}
+			if !idempotent || !policy.RetryableCodes[grpc.Code(lastErr)] {
+				return lastErr
+			}
+			if attempt >= policy.MaxAttempts-1 {
+				break
+			}
+			if policy.Budget != nil && !policy.Budget.take() {
+				break
+			}
+
+			backoff := nextBackoff(policy, attempt)
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+				break
+			}
+			retryMetrics.retries.Inc(method, grpc.Code(lastErr))
+			time.Sleep(backoff)
+		}
+		return lastErr
+	}
+}
+
+// tracingUnaryClientInterceptor passes the call through unchanged. The
+// requuid reaches the server as outgoing gRPC metadata only where the
+// calling method attached it with attachTraceMetadata.
+func tracingUnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// retryStreamClientInterceptor retries establishing a streaming call (the
+// List*, Watch* and batch RPCs) on a retryable code, with the same budget,
+// backoff and deadline checks as retryUnaryClientInterceptor; unlike the
+// unary interceptor it does not check isIdempotentMethod and does not apply
+// PerCallTimeout. Once the stream is established, retrying mid-stream is not
+// attempted: listDevices/listServices/listVolumes return a mid-stream error
+// to their caller without restarting the stream.
+func retryStreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		policy := retryPolicyFromContext(ctx)
+
+		var lastErr error
+		var stream grpc.ClientStream
+		for attempt := 0; attempt < policy.MaxAttempts; attempt++ {
+			stream, lastErr = streamer(ctx, desc, cc, method, opts...)
+			if lastErr == nil {
+				if policy.Budget != nil {
+					policy.Budget.deposit()
+				}
+				return stream, nil
+			}
+
+			if isCanceledOrDeadlineExceeded(lastErr) {
+				return nil, lastErr
+			}
+			if !policy.RetryableCodes[grpc.Code(lastErr)] {
+				return nil, lastErr
+			}
+			if attempt >= policy.MaxAttempts-1 {
+				break
+			}
+			if policy.Budget != nil && !policy.Budget.take() {
+				break
+			}
+
+			backoff := nextBackoff(policy, attempt)
+			if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+				break
+			}
+			retryMetrics.retries.Inc(method, grpc.Code(lastErr))
+			time.Sleep(backoff)
+		}
+		return nil, lastErr
+	}
+}
+
+// retryCounter is a minimal retries_total{method,code} counter. It is kept
+// dependency-free (no prometheus/client_golang import) so this package
+// doesn't force a metrics backend on every consumer; RetryMetricsSnapshot
+// lets a caller that does run Prometheus copy these into its own
+// CounterVec on a timer.
+type retryCounter struct {
+	mu     sync.Mutex
+	counts map[string]uint64
+}
+
+func newRetryCounter() *retryCounter {
+	return &retryCounter{counts: make(map[string]uint64)}
+}
+
+func (r *retryCounter) Inc(method string, code codes.Code) {
+	key := method + "|" + code.String()
+	r.mu.Lock()
+	r.counts[key]++
+	r.mu.Unlock()
+}
+
+func (r *retryCounter) snapshot() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]uint64, len(r.counts))
+	for k, v := range r.counts {
+		out[k] = v
+	}
+	return out
+}
+
+// simpleCounter is retryCounter's unlabeled counterpart, used for
+// retry_budget_exhausted_total.
+type simpleCounter struct {
+	count uint64
+}
+
+func (c *simpleCounter) Inc() { atomic.AddUint64(&c.count, 1) }
+
+func (c *simpleCounter) value() uint64 { return atomic.LoadUint64(&c.count) }
+
+var retryMetrics = struct {
+	retries         *retryCounter
+	budgetExhausted *simpleCounter
+}{
+	retries:         newRetryCounter(),
+	budgetExhausted: &simpleCounter{},
+}
+
+// RetryMetricsSnapshot returns the current retries_total{method,code}
+// counts (keyed as "method|code") and the retry_budget_exhausted_total
+// count, for a caller to copy into its own metrics backend.
+func RetryMetricsSnapshot() (retriesTotal map[string]uint64, retryBudgetExhaustedTotal uint64) {
+	return retryMetrics.retries.snapshot(), retryMetrics.budgetExhausted.value()
+}
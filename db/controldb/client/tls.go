@@ -0,0 +1,110 @@
+package controldbcli
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// ControlDBTLSConfig holds the CA bundle, client cert and key used to dial
+// ControlDBServer over mutual TLS. It is a first-class, per-client option
+// rather than a process-global so different clusters/tenants can use
+// different credentials against the same binary.
+type ControlDBTLSConfig struct {
+	// CAFile is the PEM-encoded CA bundle used to verify the server cert.
+	CAFile string
+	// CertFile/KeyFile are the PEM-encoded client certificate and key
+	// presented for mutual auth. Both are optional; if empty, the client
+	// authenticates the server only.
+	CertFile string
+	KeyFile  string
+	// ServerNameOverride overrides the server name used during the TLS
+	// handshake's SNI and certificate verification, useful when dialing by
+	// IP. Defaults to the dial address's host when empty.
+	ServerNameOverride string
+	// VerifyServerSAN, if set, is matched against the server cert's SAN
+	// entries (SPIFFE-style identity) in place of standard hostname
+	// verification: the chain is still verified against CAFile, but the
+	// dial host and ServerNameOverride are not checked against the cert.
+	VerifyServerSAN string
+}
+
+// TransportCredentials builds the grpc transport credentials for this
+// config, loading the CA bundle and, if configured, the client cert/key.
+func (c *ControlDBTLSConfig) TransportCredentials() (credentials.TransportCredentials, error) {
+	caData, err := ioutil.ReadFile(c.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("controldbcli: read CA file %s: %v", c.CAFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("controldbcli: no valid certs found in CA file %s", c.CAFile)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    pool,
+		ServerName: c.ServerNameOverride,
+	}
+
+	if c.CertFile != "" && c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("controldbcli: load client cert/key: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.VerifyServerSAN != "" {
+		tlsConfig.InsecureSkipVerify = true
+		verify := c.VerifyServerSAN
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyServerSAN(rawCerts, pool, verify)
+		}
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// verifyServerSAN parses the leaf certificate and checks that
+// VerifyServerSAN appears among its DNS SANs or URI SANs (the latter is how
+// SPIFFE IDs, e.g. "spiffe://cluster/controldb", are typically encoded).
+func verifyServerSAN(rawCerts [][]byte, roots *x509.CertPool, expectedSAN string) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("controldbcli: server presented no certificate")
+	}
+
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return fmt.Errorf("controldbcli: parse server certificate: %v", err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, raw := range rawCerts[1:] {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("controldbcli: parse server intermediate certificate: %v", err)
+		}
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{Roots: roots, Intermediates: intermediates}
+	if _, err := leaf.Verify(opts); err != nil {
+		return fmt.Errorf("controldbcli: server certificate verify failed: %v", err)
+	}
+
+	for _, name := range leaf.DNSNames {
+		if name == expectedSAN {
+			return nil
+		}
+	}
+	for _, uri := range leaf.URIs {
+		if uri.String() == expectedSAN {
+			return nil
+		}
+	}
+	return fmt.Errorf("controldbcli: server identity %q not found in certificate SANs", expectedSAN)
+}
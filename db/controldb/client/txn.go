@@ -0,0 +1,125 @@
+package controldbcli
+
+import (
+	"github.com/golang/glog"
+	"golang.org/x/net/context"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db/controldb"
+	pb "github.com/cloudstax/firecamp/db/controldb/protocols"
+	"github.com/cloudstax/firecamp/utils"
+)
+
+// Cmp is a single condition evaluated server-side before a Txn's operations
+// are applied, modeled on etcd's mini-transaction Cmp.
+type Cmp struct {
+	pb *pb.Compare
+}
+
+// CompareRevision builds a Cmp that holds only if key's current resource
+// revision equals rev, the building block for the "create a Volume and bump
+// ServiceAttr's revision" style of atomic, conflict-detecting write.
+func CompareRevision(key string, rev int64) Cmp {
+	return Cmp{pb: &pb.Compare{Key: key, Revision: rev}}
+}
+
+// Op is a single write queued in a Txn's Then/Else branch.
+type Op struct {
+	pb *pb.TxnOp
+}
+
+// CreateVolumeOp queues a CreateVolume write.
+func CreateVolumeOp(vol *common.Volume) Op {
+	return Op{pb: &pb.TxnOp{CreateVolume: controldb.GenPbVolume(vol)}}
+}
+
+// UpdateServiceAttrOp queues an UpdateServiceAttr write.
+func UpdateServiceAttrOp(oldAttr, newAttr *common.ServiceAttr) Op {
+	return Op{pb: &pb.TxnOp{UpdateServiceAttr: &pb.UpdateServiceAttrRequest{
+		OldAttr: controldb.GenPbServiceAttr(oldAttr),
+		NewAttr: controldb.GenPbServiceAttr(newAttr),
+	}}}
+}
+
+// DeleteConfigFileOp queues a DeleteConfigFile write.
+func DeleteConfigFileOp(serviceUUID, fileID string) Op {
+	return Op{pb: &pb.TxnOp{DeleteConfigFile: &pb.ConfigFileKey{
+		ServiceUUID: serviceUUID,
+		FileID:      fileID,
+	}}}
+}
+
+// Txn builds a single atomic, conditional, multi-operation write, modeled on
+// etcd's Txn: If every Cmp holds, the Then ops are applied; otherwise the
+// Else ops are. Commit submits it as one RPC, going through the same
+// retry/tracing interceptors as every other ControlDBCli call.
+type Txn struct {
+	c     *ControlDBCli
+	cmps  []Cmp
+	thens []Op
+	elses []Op
+}
+
+// Txn starts building a new transaction.
+func (c *ControlDBCli) Txn() *Txn {
+	return &Txn{c: c}
+}
+
+// If adds conditions that must all hold for Then to apply.
+func (t *Txn) If(cmps ...Cmp) *Txn {
+	t.cmps = append(t.cmps, cmps...)
+	return t
+}
+
+// Then adds the ops applied when every If condition holds.
+func (t *Txn) Then(ops ...Op) *Txn {
+	t.thens = append(t.thens, ops...)
+	return t
+}
+
+// Else adds the ops applied when at least one If condition fails.
+func (t *Txn) Else(ops ...Op) *Txn {
+	t.elses = append(t.elses, ops...)
+	return t
+}
+
+// TxnResponse reports whether the If branch succeeded, so the caller can
+// tell a committed Then from a committed Else.
+type TxnResponse struct {
+	Succeeded bool
+}
+
+// Commit submits the transaction as a single RPC.
+func (t *Txn) Commit(ctx context.Context) (*TxnResponse, error) {
+	requuid := utils.GenRequestUUID()
+	ctx = utils.NewRequestContext(ctx, requuid)
+
+	req := &pb.TxnRequest{
+		Compare: make([]*pb.Compare, len(t.cmps)),
+		Success: make([]*pb.TxnOp, len(t.thens)),
+		Failure: make([]*pb.TxnOp, len(t.elses)),
+	}
+	for i, cmp := range t.cmps {
+		req.Compare[i] = cmp.pb
+	}
+	for i, op := range t.thens {
+		req.Success[i] = op.pb
+	}
+	for i, op := range t.elses {
+		req.Failure[i] = op.pb
+	}
+
+	cli := t.c.getCli()
+	resp, err := cli.dbcli.Txn(ctx, req)
+	if err == nil {
+		glog.Infoln("Txn committed, succeeded", resp.Succeeded, "requuid", requuid)
+		return &TxnResponse{Succeeded: resp.Succeeded}, nil
+	}
+
+	glog.Errorln("Txn error", err, "requuid", requuid)
+	if t.c.isApplicationError(err) {
+		return nil, t.c.checkAndConvertError(err)
+	}
+	t.c.markClientFailed(cli)
+	return nil, err
+}
@@ -0,0 +1,215 @@
+package controldbcli
+
+import (
+	"io"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/golang/glog"
+
+	"github.com/cloudstax/firecamp/db/controldb"
+	pb "github.com/cloudstax/firecamp/db/controldb/protocols"
+	"github.com/cloudstax/firecamp/utils"
+)
+
+// ResourceType identifies which collection a Watch call follows.
+type ResourceType int
+
+const (
+	ResourceTypeService    ResourceType = 0
+	ResourceTypeVolume     ResourceType = 1
+	ResourceTypeConfigFile ResourceType = 2
+)
+
+// WatchEventType is the kind of change a WatchEvent reports.
+type WatchEventType int
+
+const (
+	// WatchEventPut is a create or update of the resource at Key.
+	WatchEventPut WatchEventType = iota
+	// WatchEventDelete is the removal of the resource at Key.
+	WatchEventDelete
+	// WatchEventResyncRequired is sent when the server can no longer serve
+	// events since SinceRevision, e.g. because its history was compacted
+	// past that point. The caller must re-list the resource and restart
+	// the watch from the revision the re-list observed.
+	WatchEventResyncRequired
+)
+
+// WatchEvent is one change notification delivered on the channel Watch
+// returns. Object is the decoded common.Service/common.Volume/
+// common.ConfigFile for WatchEventPut, nil for the other event types.
+type WatchEvent struct {
+	Type     WatchEventType
+	Key      string
+	Revision int64
+	Object   interface{}
+}
+
+// watchStreamRetryBackoff is the pause between a dropped watch stream and
+// the client re-dialing it, separate from RetryPolicy.InitialBackoff since a watch
+// reconnect is a long-lived stream re-establishment, not a single RPC retry.
+const watchStreamRetryBackoff = 1 * time.Second
+
+// Watch follows resourceType (ResourceTypeService/Volume/ConfigFile) under
+// scope (a cluster name for services, a service UUID for volumes and config
+// files), starting after sinceRevision (0 for "from the beginning"). The
+// returned channel is closed when ctx is canceled; the caller owns ctx's
+// lifetime.
+//
+// On a transport-level disconnect, Watch transparently re-dials the
+// server-streaming RPC, resuming from the last revision it observed rather
+// than sinceRevision, so the caller never sees duplicate events for a
+// revision it has already been given. If the server reports that the
+// resume point has been compacted out of its history, Watch emits a single
+// WatchEventResyncRequired and stops: the caller must re-list the resource
+// and call Watch again with the revision the re-list observed.
+func (c *ControlDBCli) Watch(ctx context.Context, resourceType ResourceType, scope string, sinceRevision int64) (<-chan WatchEvent, error) {
+	events := make(chan WatchEvent, 32)
+
+	go c.runWatch(ctx, resourceType, scope, sinceRevision, events)
+
+	return events, nil
+}
+
+func (c *ControlDBCli) runWatch(ctx context.Context, resourceType ResourceType, scope string, sinceRevision int64, events chan<- WatchEvent) {
+	defer close(events)
+
+	requuid := utils.GenRequestUUID()
+	wctx := utils.NewRequestContext(ctx, requuid)
+	revision := sinceRevision
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		stream, err := c.openWatchStream(wctx, resourceType, scope, revision)
+		if err != nil {
+			glog.Errorln("Watch open stream error", err, "resourceType", resourceType,
+				"scope", scope, "revision", revision, "requuid", requuid)
+			if !waitOrDone(ctx, watchStreamRetryBackoff) {
+				return
+			}
+			continue
+		}
+
+		lastRevision, resyncRequired, err := c.pumpWatchStream(stream, events)
+		if resyncRequired {
+			events <- WatchEvent{Type: WatchEventResyncRequired}
+			return
+		}
+		if lastRevision > revision {
+			revision = lastRevision
+		}
+		if err == io.EOF || err == nil {
+			// server closed the stream cleanly, e.g. load rebalancing; resume.
+		} else {
+			glog.Errorln("Watch stream error", err, "resourceType", resourceType,
+				"scope", scope, "revision", revision, "requuid", requuid)
+		}
+
+		if !waitOrDone(ctx, watchStreamRetryBackoff) {
+			return
+		}
+	}
+}
+
+// watchRecvStream is the common shape of the three generated
+// ControlDBService_Watch*Client stream types; pumpWatchStream is written
+// against it so it doesn't need to be duplicated per resource type.
+type watchRecvStream interface {
+	Recv() (*pb.WatchResponse, error)
+}
+
+func (c *ControlDBCli) openWatchStream(ctx context.Context, resourceType ResourceType, scope string, sinceRevision int64) (watchRecvStream, error) {
+	cli := c.getCli()
+	req := &pb.WatchRequest{
+		Scope:         scope,
+		SinceRevision: sinceRevision,
+	}
+
+	switch resourceType {
+	case ResourceTypeService:
+		return cli.dbcli.WatchServices(ctx, req)
+	case ResourceTypeVolume:
+		return cli.dbcli.WatchVolumes(ctx, req)
+	case ResourceTypeConfigFile:
+		return cli.dbcli.WatchConfigFiles(ctx, req)
+	default:
+		return nil, grpcUnimplementedResourceType(resourceType)
+	}
+}
+
+// pumpWatchStream reads events off stream until it errors out or the server
+// reports the watch needs a resync, returning the highest revision observed
+// so the caller can resume from it.
+func (c *ControlDBCli) pumpWatchStream(stream watchRecvStream, events chan<- WatchEvent) (lastRevision int64, resyncRequired bool, err error) {
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return lastRevision, false, nil
+		}
+		if err != nil {
+			return lastRevision, false, err
+		}
+
+		if resp.CompactedAway {
+			return lastRevision, true, nil
+		}
+
+		ev := WatchEvent{
+			Key:      resp.Key,
+			Revision: resp.Revision,
+		}
+		switch resp.EventType {
+		case pb.WatchResponse_DELETE:
+			ev.Type = WatchEventDelete
+		default:
+			ev.Type = WatchEventPut
+			ev.Object = decodeWatchObject(resp)
+		}
+
+		events <- ev
+		lastRevision = resp.Revision
+	}
+}
+
+func decodeWatchObject(resp *pb.WatchResponse) interface{} {
+	switch {
+	case resp.Service != nil:
+		return controldb.GenDbService(resp.Service)
+	case resp.Volume != nil:
+		return controldb.GenDbVolume(resp.Volume)
+	case resp.ConfigFile != nil:
+		return controldb.GenDbConfigFile(resp.ConfigFile)
+	default:
+		return nil
+	}
+}
+
+func grpcUnimplementedResourceType(resourceType ResourceType) error {
+	return &unsupportedResourceTypeError{resourceType: resourceType}
+}
+
+type unsupportedResourceTypeError struct {
+	resourceType ResourceType
+}
+
+func (e *unsupportedResourceTypeError) Error() string {
+	return "controldbcli: unsupported watch resource type"
+}
+
+// waitOrDone pauses for d, returning false early (without waiting out d) if
+// ctx is canceled first.
+func waitOrDone(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+import "time"
+
+// DeploymentType distinguishes the update strategies a Deployment row can
+// record progress for.
+type DeploymentType string
+
+const (
+	DeploymentTypeRecreate      DeploymentType = "RECREATE"
+	DeploymentTypeRollingUpdate DeploymentType = "ROLLING_UPDATE"
+	DeploymentTypeBlueGreen     DeploymentType = "BLUE_GREEN"
+)
+
+// DeploymentStatus is the lifecycle state of a Deployment row.
+type DeploymentStatus string
+
+const (
+	DeploymentStatusInProgress DeploymentStatus = "IN_PROGRESS"
+	DeploymentStatusCompleted  DeploymentStatus = "COMPLETED"
+	DeploymentStatusFailed     DeploymentStatus = "FAILED"
+)
+
+// Deployment records the progress of an UpdateService/RollingRestart/
+// BlueGreenSwitch call so an interrupted rollout can be resumed from where
+// it left off instead of restarting from member 0.
+type Deployment struct {
+	ServiceUUID string
+	Type        DeploymentType
+	Status      DeploymentStatus
+
+	// MemberOrder is the deterministic order (by member index, respecting AZ
+	// spread) the rollout walks members in.
+	MemberOrder []string
+	// CompletedMembers is the prefix of MemberOrder already rolled.
+	CompletedMembers []string
+
+	// GreenMemberSuffix is set for DeploymentTypeBlueGreen while the green
+	// member set exists alongside the blue set, e.g. "-green".
+	GreenMemberSuffix string
+
+	StartedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NextMember returns the next member name to roll, or "" if the deployment
+// has already walked every member in MemberOrder.
+func (d *Deployment) NextMember() string {
+	if len(d.CompletedMembers) >= len(d.MemberOrder) {
+		return ""
+	}
+	return d.MemberOrder[len(d.CompletedMembers)]
+}
+
+// MarkMemberDone appends member to CompletedMembers, advancing the rollout.
+func (d *Deployment) MarkMemberDone(member string) {
+	d.CompletedMembers = append(d.CompletedMembers, member)
+	d.UpdatedAt = time.Now()
+}
+
+// DeploymentDB is the persistence surface UpdateService/RollingRestart/
+// BlueGreenSwitch need to make a rollout resumable.
+type DeploymentDB interface {
+	CreateDeployment(d *Deployment) error
+	GetDeployment(serviceUUID string) (*Deployment, error)
+	UpdateDeployment(d *Deployment) error
+	DeleteDeployment(serviceUUID string) error
+}
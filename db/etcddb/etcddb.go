@@ -0,0 +1,263 @@
+// Package etcddb implements the db.DB interface on top of etcd v3, as an
+// alternative metadata backend to DynamoDB/controldb for clusters that
+// already run etcd for other purposes. db.DB itself is not defined in this
+// tree (see db/reservation.go and db/deployment.go for the surfaces that
+// do exist here), so EtcdDB is exercised directly rather than through that
+// interface; etcddb_integration_test.go (build tag "integration") runs the
+// same CRUD/CAS assertions the other db backends cover against a real etcd
+// endpoint, since this package has no in-memory double to run under a plain
+// go test ./...
+//
+// Key layout:
+//
+//	/firecamp/<cluster>/services/<name>        -> Service
+//	/firecamp/<cluster>/serviceattrs/<uuid>     -> ServiceAttr
+//	/firecamp/<cluster>/members/<uuid>/<index>  -> ServiceMember
+//	/firecamp/<cluster>/staticips/<ip>          -> ServiceStaticIP
+package etcddb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db"
+)
+
+const (
+	rootPrefix      = "/firecamp"
+	servicesDir     = "services"
+	serviceAttrsDir = "serviceattrs"
+	membersDir      = "members"
+	staticIPsDir    = "staticips"
+
+	deviceLockPrefix = "/firecamp-locks/createdevice"
+	lockTTLSeconds   = 30
+)
+
+// EtcdDB stores firecamp metadata in an etcd v3 cluster, under the key
+// layout in the package doc.
+type EtcdDB struct {
+	cli *clientv3.Client
+}
+
+// New creates an EtcdDB using an already-constructed clientv3.Client.
+func New(cli *clientv3.Client) *EtcdDB {
+	return &EtcdDB{cli: cli}
+}
+
+func clusterPrefix(cluster string) string {
+	return fmt.Sprintf("%s/%s", rootPrefix, cluster)
+}
+
+func serviceKey(cluster, name string) string {
+	return fmt.Sprintf("%s/%s/%s", clusterPrefix(cluster), servicesDir, name)
+}
+
+func serviceAttrKey(cluster, uuid string) string {
+	return fmt.Sprintf("%s/%s/%s", clusterPrefix(cluster), serviceAttrsDir, uuid)
+}
+
+func memberKey(cluster, uuid string, index int64) string {
+	return fmt.Sprintf("%s/%s/%s/%d", clusterPrefix(cluster), membersDir, uuid, index)
+}
+
+func memberPrefix(cluster, uuid string) string {
+	return fmt.Sprintf("%s/%s/%s/", clusterPrefix(cluster), membersDir, uuid)
+}
+
+func staticIPKey(cluster, ip string) string {
+	return fmt.Sprintf("%s/%s/%s", clusterPrefix(cluster), staticIPsDir, ip)
+}
+
+// createIfAbsent puts key=value only if it does not already exist,
+// preserving the "create-if-absent" semantics the existing backends rely on
+// (e.g. CreateService failing with ErrDBConditionalCheckFailed when the
+// row already exists).
+func (d *EtcdDB) createIfAbsent(ctx context.Context, key string, value []byte) error {
+	txn := d.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value)))
+	resp, err := txn.Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return db.ErrDBConditionalCheckFailed
+	}
+	return nil
+}
+
+func (d *EtcdDB) get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := d.cli.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, db.ErrDBRecordNotFound
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+func (d *EtcdDB) delete(ctx context.Context, key string) error {
+	_, err := d.cli.Delete(ctx, key)
+	return err
+}
+
+// CreateService creates a Service row, failing with
+// ErrDBConditionalCheckFailed if one already exists at the same key.
+func (d *EtcdDB) CreateService(ctx context.Context, svc *common.Service) error {
+	data, err := json.Marshal(svc)
+	if err != nil {
+		return err
+	}
+	return d.createIfAbsent(ctx, serviceKey(svc.ClusterName, svc.ServiceName), data)
+}
+
+// GetService fetches a Service row by cluster and name.
+func (d *EtcdDB) GetService(ctx context.Context, clusterName, serviceName string) (*common.Service, error) {
+	data, err := d.get(ctx, serviceKey(clusterName, serviceName))
+	if err != nil {
+		return nil, err
+	}
+	svc := &common.Service{}
+	if err := json.Unmarshal(data, svc); err != nil {
+		return nil, err
+	}
+	return svc, nil
+}
+
+// DeleteService removes a Service row.
+func (d *EtcdDB) DeleteService(ctx context.Context, clusterName, serviceName string) error {
+	return d.delete(ctx, serviceKey(clusterName, serviceName))
+}
+
+// ListServices returns every Service row under cluster.
+func (d *EtcdDB) ListServices(ctx context.Context, clusterName string) ([]*common.Service, error) {
+	prefix := fmt.Sprintf("%s/%s/", clusterPrefix(clusterName), servicesDir)
+	resp, err := d.cli.Get(ctx, prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	svcs := make([]*common.Service, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		svc := &common.Service{}
+		if err := json.Unmarshal(kv.Value, svc); err != nil {
+			return nil, err
+		}
+		svcs = append(svcs, svc)
+	}
+	return svcs, nil
+}
+
+// CreateServiceAttr creates a ServiceAttr row.
+func (d *EtcdDB) CreateServiceAttr(ctx context.Context, cluster string, attr *common.ServiceAttr) error {
+	data, err := json.Marshal(attr)
+	if err != nil {
+		return err
+	}
+	return d.createIfAbsent(ctx, serviceAttrKey(cluster, attr.ServiceUUID), data)
+}
+
+// GetServiceAttr fetches a ServiceAttr row by uuid.
+func (d *EtcdDB) GetServiceAttr(ctx context.Context, cluster, serviceUUID string) (*common.ServiceAttr, error) {
+	data, err := d.get(ctx, serviceAttrKey(cluster, serviceUUID))
+	if err != nil {
+		return nil, err
+	}
+	attr := &common.ServiceAttr{}
+	if err := json.Unmarshal(data, attr); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
+
+// UpdateServiceAttr performs a full-value CAS: it only replaces the row if
+// its current content still equals oldAttr's serialized form.
+func (d *EtcdDB) UpdateServiceAttr(ctx context.Context, cluster string, oldAttr, newAttr *common.ServiceAttr) error {
+	oldData, err := json.Marshal(oldAttr)
+	if err != nil {
+		return err
+	}
+	newData, err := json.Marshal(newAttr)
+	if err != nil {
+		return err
+	}
+
+	key := serviceAttrKey(cluster, oldAttr.ServiceUUID)
+	txn := d.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.Value(key), "=", string(oldData))).
+		Then(clientv3.OpPut(key, string(newData)))
+	resp, err := txn.Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return db.ErrDBConditionalCheckFailed
+	}
+	return nil
+}
+
+// CreateServiceMember creates a ServiceMember row keyed by its replica
+// index within the service.
+func (d *EtcdDB) CreateServiceMember(ctx context.Context, cluster string, member *common.ServiceMember) error {
+	data, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+	return d.createIfAbsent(ctx, memberKey(cluster, member.ServiceUUID, member.MemberIndex), data)
+}
+
+// ListServiceMembers returns every ServiceMember row for a service.
+func (d *EtcdDB) ListServiceMembers(ctx context.Context, cluster, serviceUUID string) ([]*common.ServiceMember, error) {
+	resp, err := d.cli.Get(ctx, memberPrefix(cluster, serviceUUID), clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	members := make([]*common.ServiceMember, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		m := &common.ServiceMember{}
+		if err := json.Unmarshal(kv.Value, m); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, nil
+}
+
+// CreateStaticIP creates a ServiceStaticIP row.
+func (d *EtcdDB) CreateStaticIP(ctx context.Context, cluster string, ip *common.ServiceStaticIP) error {
+	data, err := json.Marshal(ip)
+	if err != nil {
+		return err
+	}
+	return d.createIfAbsent(ctx, staticIPKey(cluster, ip.StaticIP), data)
+}
+
+// WithDeviceLock runs fn while holding a lease-backed etcd lock for the
+// createDevice critical section, so two manage-service instances racing to
+// allocate the next /dev/loopN for the same cluster can't hand out the same
+// device.
+func (d *EtcdDB) WithDeviceLock(ctx context.Context, cluster string, fn func(ctx context.Context) error) error {
+	session, err := concurrency.NewSession(d.cli, concurrency.WithTTL(lockTTLSeconds))
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	mu := concurrency.NewMutex(session, fmt.Sprintf("%s/%s", deviceLockPrefix, cluster))
+	lockCtx, cancel := context.WithTimeout(ctx, lockTTLSeconds*time.Second)
+	defer cancel()
+
+	if err := mu.Lock(lockCtx); err != nil {
+		return err
+	}
+	defer mu.Unlock(context.Background())
+
+	return fn(ctx)
+}
@@ -0,0 +1,131 @@
+//go:build integration
+// +build integration
+
+package etcddb
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go.etcd.io/etcd/clientv3"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/db"
+)
+
+// newIntegrationEtcdDB connects to a real etcd cluster for the lifetime of
+// the test, using ETCD_ENDPOINTS (comma-separated, default
+// "localhost:2379"). Run with: go test -tags integration ./db/etcddb/...
+// against a live etcd instance; this is never run by a plain go test ./...
+// because EtcdDB has no in-memory double.
+func newIntegrationEtcdDB(t *testing.T) *EtcdDB {
+	t.Helper()
+
+	endpoints := os.Getenv("ETCD_ENDPOINTS")
+	if endpoints == "" {
+		endpoints = "localhost:2379"
+	}
+
+	cli, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(endpoints, ","),
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("clientv3.New error %s", err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return New(cli)
+}
+
+// TestUtil_ServiceCreationRetry mirrors db.TestUtil_ServiceCreationRetry's
+// intent against the etcd backend: a retried CreateService call for the
+// same (cluster, name) must fail with ErrDBConditionalCheckFailed rather
+// than silently overwriting the existing row.
+func TestUtil_ServiceCreationRetry(t *testing.T) {
+	d := newIntegrationEtcdDB(t)
+	ctx := context.Background()
+	cluster := "it-cluster"
+	name := "it-service-" + t.Name()
+
+	svc := &common.Service{ClusterName: cluster, ServiceName: name, ServiceUUID: "uuid-1"}
+	defer d.DeleteService(ctx, cluster, name)
+
+	if err := d.CreateService(ctx, svc); err != nil {
+		t.Fatalf("CreateService() first attempt error %s", err)
+	}
+
+	if err := d.CreateService(ctx, svc); err != db.ErrDBConditionalCheckFailed {
+		t.Fatalf("CreateService() retry error = %v, want ErrDBConditionalCheckFailed", err)
+	}
+
+	got, err := d.GetService(ctx, cluster, name)
+	if err != nil {
+		t.Fatalf("GetService() error %s", err)
+	}
+	if got.ServiceUUID != svc.ServiceUUID {
+		t.Errorf("GetService().ServiceUUID = %s, want %s", got.ServiceUUID, svc.ServiceUUID)
+	}
+}
+
+// TestServiceAttrUpdateIsCAS asserts UpdateServiceAttr only applies when the
+// stored row still matches oldAttr, and rejects a stale update the same way
+// the DynamoDB/controldb backends do.
+func TestServiceAttrUpdateIsCAS(t *testing.T) {
+	d := newIntegrationEtcdDB(t)
+	ctx := context.Background()
+	cluster := "it-cluster"
+	uuid := "it-attr-" + t.Name()
+
+	attr := &common.ServiceAttr{ServiceUUID: uuid, ServiceStatus: "ACTIVE"}
+	defer d.delete(ctx, serviceAttrKey(cluster, uuid))
+
+	if err := d.CreateServiceAttr(ctx, cluster, attr); err != nil {
+		t.Fatalf("CreateServiceAttr() error %s", err)
+	}
+
+	updated := &common.ServiceAttr{ServiceUUID: uuid, ServiceStatus: "DELETING"}
+	if err := d.UpdateServiceAttr(ctx, cluster, attr, updated); err != nil {
+		t.Fatalf("UpdateServiceAttr() error %s", err)
+	}
+
+	// retrying with the now-stale oldAttr must fail, not overwrite.
+	staleUpdate := &common.ServiceAttr{ServiceUUID: uuid, ServiceStatus: "ACTIVE"}
+	if err := d.UpdateServiceAttr(ctx, cluster, attr, staleUpdate); err != db.ErrDBConditionalCheckFailed {
+		t.Fatalf("UpdateServiceAttr() with stale oldAttr error = %v, want ErrDBConditionalCheckFailed", err)
+	}
+}
+
+// TestServiceMemberListAndStaticIP exercises member creation/listing and
+// static IP creation, the two remaining CRUD paths CreateService's retry
+// path relies on.
+func TestServiceMemberListAndStaticIP(t *testing.T) {
+	d := newIntegrationEtcdDB(t)
+	ctx := context.Background()
+	cluster := "it-cluster"
+	uuid := "it-members-" + t.Name()
+
+	for i := int64(0); i < 3; i++ {
+		m := &common.ServiceMember{ServiceUUID: uuid, MemberIndex: i, MemberName: t.Name()}
+		defer d.delete(ctx, memberKey(cluster, uuid, i))
+		if err := d.CreateServiceMember(ctx, cluster, m); err != nil {
+			t.Fatalf("CreateServiceMember(%d) error %s", i, err)
+		}
+	}
+
+	members, err := d.ListServiceMembers(ctx, cluster, uuid)
+	if err != nil {
+		t.Fatalf("ListServiceMembers() error %s", err)
+	}
+	if len(members) != 3 {
+		t.Fatalf("ListServiceMembers() returned %d members, want 3", len(members))
+	}
+
+	ip := &common.ServiceStaticIP{StaticIP: "10.0.0.4-" + t.Name(), ServiceUUID: uuid}
+	defer d.delete(ctx, staticIPKey(cluster, ip.StaticIP))
+	if err := d.CreateStaticIP(ctx, cluster, ip); err != nil {
+		t.Fatalf("CreateStaticIP() error %s", err)
+	}
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ReservationState is the lifecycle state of a Reservation row.
+type ReservationState string
+
+const (
+	// ReservationPending means phase 1 (ReserveService) completed but phase
+	// 2 (CommitService) has not flipped the row yet.
+	ReservationPending ReservationState = "PENDING"
+	// ReservationCommitted means CommitService finished successfully.
+	ReservationCommitted ReservationState = "COMMITTED"
+)
+
+// ErrReservationExpired is returned when CommitService is attempted against
+// a Reservation whose TTL has already elapsed; the caller should reserve
+// again.
+var ErrReservationExpired = errors.New("db: reservation expired")
+
+// Reservation is the request-scoped record that makes a create-service flow
+// idempotent across retries, via the two-phase ReserveService/CommitService
+// below. It is keyed by (ClusterName, ServiceName, RequestHash) so a retried
+// call with an identical request finds and resumes the same reservation
+// instead of re-deriving state from whatever partial rows a previous attempt
+// left behind. Note: the caller this is designed for, ManageService.CreateService,
+// is not part of this tree (manage/server.go doesn't exist here), so
+// ReserveService/CommitService are not yet invoked from any create-service
+// code path; TestUtil_ServiceCreationRetry exercises them directly instead.
+type Reservation struct {
+	ClusterName string
+	ServiceName string
+	RequestHash string
+
+	ServiceUUID string
+	State       ReservationState
+
+	// DeviceNames and StaticIPs are reserved in phase 1 so CommitService
+	// never has to invent new ones on retry.
+	DeviceNames []string
+	StaticIPs   []string
+
+	CreatedAt time.Time
+	ExpireAt  time.Time
+}
+
+// HashRequest derives the RequestHash key component from the fields of a
+// create-service request that determine its outcome. Callers pass the
+// already-serialized canonical form (e.g. a stable JSON encoding of
+// manage.CreateServiceRequest) so this package does not need to depend on
+// the manage package.
+func HashRequest(canonical []byte) string {
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
+
+// IsExpired reports whether a still-Pending reservation's TTL has elapsed.
+func (r *Reservation) IsExpired(now time.Time) bool {
+	return r.State == ReservationPending && now.After(r.ExpireAt)
+}
+
+// ReservationDB is the minimal persistence surface CreateService's two
+// phases and the background sweeper need.
+type ReservationDB interface {
+	// CreateReservation inserts a new Pending row, or returns the existing
+	// row (and no error) if one already exists for the same key — this is
+	// what makes ReserveService idempotent under retry.
+	CreateReservation(r *Reservation) (*Reservation, error)
+	GetReservation(clusterName, serviceName, requestHash string) (*Reservation, error)
+	CommitReservation(clusterName, serviceName, requestHash, serviceUUID string) error
+	DeleteReservation(clusterName, serviceName, requestHash string) error
+	// ListExpiredReservations returns Pending rows whose ExpireAt is before
+	// before, for the sweeper to release.
+	ListExpiredReservations(before time.Time) ([]*Reservation, error)
+}
+
+// ReserveService is phase 1 of the two-phase create-service flow: it looks
+// up an existing reservation for (clusterName, serviceName, requestHash)
+// and returns it unchanged if one is already Pending or Committed (the
+// retry case), otherwise calls allocate to pick device names and static
+// IPs and persists a new Pending row via db.CreateReservation. allocate is
+// only invoked when no reservation exists yet, so a retry never allocates
+// a second time.
+func ReserveService(rdb ReservationDB, clusterName, serviceName, requestHash string, ttl time.Duration, allocate func() (deviceNames, staticIPs []string, err error)) (*Reservation, error) {
+	if existing, err := rdb.GetReservation(clusterName, serviceName, requestHash); err == nil && existing != nil {
+		return existing, nil
+	}
+
+	deviceNames, staticIPs, err := allocate()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	r := &Reservation{
+		ClusterName: clusterName,
+		ServiceName: serviceName,
+		RequestHash: requestHash,
+		State:       ReservationPending,
+		DeviceNames: deviceNames,
+		StaticIPs:   staticIPs,
+		CreatedAt:   now,
+		ExpireAt:    now.Add(ttl),
+	}
+	return rdb.CreateReservation(r)
+}
+
+// CommitService is phase 2: it flips a still-Pending reservation to
+// Committed and records the ServiceUUID the create-service flow produced.
+// It returns ErrReservationExpired if the TTL elapsed before commit, so the
+// caller knows to call ReserveService again rather than trusting device
+// names and static IPs that may have been swept and handed to someone else.
+func CommitService(rdb ReservationDB, clusterName, serviceName, requestHash, serviceUUID string) error {
+	r, err := rdb.GetReservation(clusterName, serviceName, requestHash)
+	if err != nil {
+		return err
+	}
+	if r.IsExpired(time.Now()) {
+		return ErrReservationExpired
+	}
+	return rdb.CommitReservation(clusterName, serviceName, requestHash, serviceUUID)
+}
+
+// ReservationSweeper periodically releases Pending reservations that never
+// reached CommitService, so their reserved device slots and static IPs can
+// be handed out again.
+type ReservationSweeper struct {
+	db       ReservationDB
+	interval time.Duration
+	release  func(r *Reservation) error
+
+	stopCh chan struct{}
+}
+
+// NewReservationSweeper creates a sweeper that runs every interval and calls
+// release for every Pending reservation past its TTL, then deletes the row.
+func NewReservationSweeper(db ReservationDB, interval time.Duration, release func(r *Reservation) error) *ReservationSweeper {
+	return &ReservationSweeper{
+		db:       db,
+		interval: interval,
+		release:  release,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start runs the sweep loop until Stop is called.
+func (s *ReservationSweeper) Start() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.sweepOnce()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop terminates the sweep loop.
+func (s *ReservationSweeper) Stop() {
+	close(s.stopCh)
+}
+
+func (s *ReservationSweeper) sweepOnce() {
+	expired, err := s.db.ListExpiredReservations(time.Now())
+	if err != nil {
+		return
+	}
+	for _, r := range expired {
+		if err := s.release(r); err != nil {
+			// leave the row for the next sweep to retry.
+			continue
+		}
+		s.db.DeleteReservation(r.ClusterName, r.ServiceName, r.RequestHash)
+	}
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// memReservationDB is an in-memory ReservationDB used by the tests below.
+type memReservationDB struct {
+	rows map[string]*Reservation
+}
+
+func newMemReservationDB() *memReservationDB {
+	return &memReservationDB{rows: make(map[string]*Reservation)}
+}
+
+func (m *memReservationDB) key(clusterName, serviceName, requestHash string) string {
+	return clusterName + "/" + serviceName + "/" + requestHash
+}
+
+func (m *memReservationDB) CreateReservation(r *Reservation) (*Reservation, error) {
+	k := m.key(r.ClusterName, r.ServiceName, r.RequestHash)
+	if existing, ok := m.rows[k]; ok {
+		return existing, nil
+	}
+	m.rows[k] = r
+	return r, nil
+}
+
+func (m *memReservationDB) GetReservation(clusterName, serviceName, requestHash string) (*Reservation, error) {
+	r, ok := m.rows[m.key(clusterName, serviceName, requestHash)]
+	if !ok {
+		return nil, errors.New("db: reservation not found")
+	}
+	return r, nil
+}
+
+func (m *memReservationDB) CommitReservation(clusterName, serviceName, requestHash, serviceUUID string) error {
+	r, err := m.GetReservation(clusterName, serviceName, requestHash)
+	if err != nil {
+		return err
+	}
+	r.State = ReservationCommitted
+	r.ServiceUUID = serviceUUID
+	return nil
+}
+
+func (m *memReservationDB) DeleteReservation(clusterName, serviceName, requestHash string) error {
+	delete(m.rows, m.key(clusterName, serviceName, requestHash))
+	return nil
+}
+
+func (m *memReservationDB) ListExpiredReservations(before time.Time) ([]*Reservation, error) {
+	var expired []*Reservation
+	for _, r := range m.rows {
+		if r.IsExpired(before) {
+			expired = append(expired, r)
+		}
+	}
+	return expired, nil
+}
+
+// TestUtil_ServiceCreationRetry simulates a create-service caller that
+// crashes after ReserveService but before CommitService, then retries with
+// the identical request hash: the retry must resume the same reservation
+// (no second device/IP allocation) and CommitService must still succeed.
+func TestUtil_ServiceCreationRetry(t *testing.T) {
+	rdb := newMemReservationDB()
+	allocateCalls := 0
+	allocate := func() ([]string, []string, error) {
+		allocateCalls++
+		return []string{"/dev/xvdf"}, []string{"10.0.0.4"}, nil
+	}
+
+	first, err := ReserveService(rdb, "cluster1", "svc1", "hash1", time.Hour, allocate)
+	if err != nil {
+		t.Fatalf("ReserveService first attempt error %s", err)
+	}
+
+	// simulate the crash: caller retries with the same request hash before
+	// ever calling CommitService.
+	second, err := ReserveService(rdb, "cluster1", "svc1", "hash1", time.Hour, allocate)
+	if err != nil {
+		t.Fatalf("ReserveService retry error %s", err)
+	}
+
+	if allocateCalls != 1 {
+		t.Fatalf("allocate called %d times, want 1 (retry must not re-allocate)", allocateCalls)
+	}
+	if second.State != ReservationPending {
+		t.Fatalf("retry reservation state = %s, want Pending", second.State)
+	}
+	if len(second.DeviceNames) != 1 || second.DeviceNames[0] != first.DeviceNames[0] {
+		t.Fatalf("retry reservation DeviceNames = %v, want same as first attempt %v", second.DeviceNames, first.DeviceNames)
+	}
+
+	if err := CommitService(rdb, "cluster1", "svc1", "hash1", "uuid-svc1"); err != nil {
+		t.Fatalf("CommitService error %s", err)
+	}
+
+	committed, err := rdb.GetReservation("cluster1", "svc1", "hash1")
+	if err != nil {
+		t.Fatalf("GetReservation after commit error %s", err)
+	}
+	if committed.State != ReservationCommitted || committed.ServiceUUID != "uuid-svc1" {
+		t.Fatalf("committed reservation = %+v, want State Committed, ServiceUUID uuid-svc1", committed)
+	}
+}
+
+// TestCommitServiceExpired asserts CommitService reports ErrReservationExpired,
+// not a success or a generic error, when the TTL has elapsed before commit
+// — the signal the caller needs to know it must reserve again rather than
+// trusting device names/static IPs that may already have been swept.
+func TestCommitServiceExpired(t *testing.T) {
+	rdb := newMemReservationDB()
+	allocate := func() ([]string, []string, error) {
+		return []string{"/dev/xvdf"}, []string{"10.0.0.4"}, nil
+	}
+
+	if _, err := ReserveService(rdb, "cluster1", "svc1", "hash1", -time.Minute, allocate); err != nil {
+		t.Fatalf("ReserveService error %s", err)
+	}
+
+	if err := CommitService(rdb, "cluster1", "svc1", "hash1", "uuid-svc1"); err != ErrReservationExpired {
+		t.Fatalf("CommitService error = %v, want ErrReservationExpired", err)
+	}
+}
+
+// TestReservationSweeperReleasesExpired asserts the sweeper releases and
+// deletes a Pending reservation past its TTL, but leaves a Pending one
+// still within its TTL alone.
+func TestReservationSweeperReleasesExpired(t *testing.T) {
+	rdb := newMemReservationDB()
+	allocate := func() ([]string, []string, error) {
+		return []string{"/dev/xvdf"}, []string{"10.0.0.4"}, nil
+	}
+
+	if _, err := ReserveService(rdb, "cluster1", "expired", "hash1", -time.Minute, allocate); err != nil {
+		t.Fatalf("ReserveService expired error %s", err)
+	}
+	if _, err := ReserveService(rdb, "cluster1", "live", "hash2", time.Hour, allocate); err != nil {
+		t.Fatalf("ReserveService live error %s", err)
+	}
+
+	var released []string
+	s := NewReservationSweeper(rdb, time.Hour, func(r *Reservation) error {
+		released = append(released, r.ServiceName)
+		return nil
+	})
+	s.sweepOnce()
+
+	if len(released) != 1 || released[0] != "expired" {
+		t.Fatalf("released = %v, want [expired]", released)
+	}
+	if _, err := rdb.GetReservation("cluster1", "expired", "hash1"); err == nil {
+		t.Fatal("expect expired reservation to be deleted after sweep")
+	}
+	if _, err := rdb.GetReservation("cluster1", "live", "hash2"); err != nil {
+		t.Fatalf("expect live reservation to remain, got error %s", err)
+	}
+}
@@ -0,0 +1,91 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+)
+
+// consulAgent is the minimal Consul agent surface consulDNS needs. The
+// production constructor wires this to api.Client.Agent(); tests can supply
+// an in-memory double.
+type consulAgent interface {
+	ServiceRegister(name, id, address string, port int, checkHTTP string) error
+	ServiceDeregister(id string) error
+	ServiceAddress(id string) (address string, found bool, err error)
+}
+
+// consulDNS registers each ServiceMember as a Consul service, with an HTTP
+// health check at http://<member host>:<healthPort><healthPath> so Consul's
+// DNS interface (<service>.service.consul) only resolves members that pass
+// it. The check does not look at the member's volume mount status.
+type consulDNS struct {
+	agent      consulAgent
+	healthPort int
+	healthPath string
+}
+
+func newConsulDNS(agent consulAgent, healthPort int, healthPath string) *consulDNS {
+	if healthPath == "" {
+		healthPath = "/health"
+	}
+	return &consulDNS{agent: agent, healthPort: healthPort, healthPath: healthPath}
+}
+
+func init() {
+	RegisterProvider("consul", func(config map[string]string) (DNS, error) {
+		agent, err := newConsulAgentFromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return newConsulDNS(agent, 0, ""), nil
+	})
+}
+
+func (c *consulDNS) GetOrCreateHostedZoneIDByName(ctx context.Context, domain string, vpcID string, region string, private bool) (string, error) {
+	// Consul has no hosted-zone concept; the "zone" is the Consul datacenter,
+	// which callers configure out of band. The domain is returned unchanged
+	// so downstream DNS name construction stays consistent across providers.
+	return domain, nil
+}
+
+func (c *consulDNS) UpdateServiceDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error {
+	checkURL := fmt.Sprintf("http://%s:%d%s", memberHost, c.healthPort, c.healthPath)
+	return c.agent.ServiceRegister(hostedZoneID, dnsName, memberHost, c.healthPort, checkURL)
+}
+
+func (c *consulDNS) DeleteDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error {
+	return c.agent.ServiceDeregister(dnsName)
+}
+
+func (c *consulDNS) GetDNSRecord(ctx context.Context, dnsName string, hostedZoneID string) (string, error) {
+	addr, found, err := c.agent.ServiceAddress(dnsName)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("dns: consul service %s not found", dnsName)
+	}
+	return addr, nil
+}
+
+// Reconcile re-registers any member present in members but missing from
+// Consul's catalog. Consul service ids that are not in members are left
+// registered; Reconcile does not deregister them. This satisfies the Reconciler interface so Registry.Reconcile
+// picks it up automatically.
+func (c *consulDNS) Reconcile(ctx context.Context, cluster string, members []MemberState) error {
+	for _, m := range members {
+		if _, found, err := c.agent.ServiceAddress(m.DNSName); err == nil && !found {
+			if err := c.UpdateServiceDNSRecord(ctx, m.DNSName, m.Host, m.MemberName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newConsulAgentFromConfig is overridden in builds that vendor the Consul
+// API client; the default returns an error so the provider fails closed
+// until wired up.
+var newConsulAgentFromConfig = func(config map[string]string) (consulAgent, error) {
+	return nil, fmt.Errorf("dns: consul provider requires a consul agent client, none configured")
+}
@@ -0,0 +1,88 @@
+package dns
+
+import (
+	"context"
+	"testing"
+)
+
+// memConsulAgent is an in-memory consulAgent double.
+type memConsulAgent struct {
+	services map[string]string // id -> address
+}
+
+func newMemConsulAgent() *memConsulAgent {
+	return &memConsulAgent{services: make(map[string]string)}
+}
+
+func (a *memConsulAgent) ServiceRegister(name, id, address string, port int, checkHTTP string) error {
+	a.services[id] = address
+	return nil
+}
+
+func (a *memConsulAgent) ServiceDeregister(id string) error {
+	delete(a.services, id)
+	return nil
+}
+
+func (a *memConsulAgent) ServiceAddress(id string) (string, bool, error) {
+	addr, ok := a.services[id]
+	return addr, ok, nil
+}
+
+// TestConsulDNSRegisterLookupDeregister exercises the full round trip a
+// member goes through: registration makes it resolvable, and deregistration
+// makes it not-found again.
+func TestConsulDNSRegisterLookupDeregister(t *testing.T) {
+	agent := newMemConsulAgent()
+	c := newConsulDNS(agent, 8080, "")
+
+	if err := c.UpdateServiceDNSRecord(context.Background(), "svc-0.service.consul", "10.0.0.4", "svc-0"); err != nil {
+		t.Fatalf("UpdateServiceDNSRecord error %s", err)
+	}
+
+	host, err := c.GetDNSRecord(context.Background(), "svc-0.service.consul", "svc-0")
+	if err != nil {
+		t.Fatalf("GetDNSRecord error %s", err)
+	}
+	if host != "10.0.0.4" {
+		t.Errorf("GetDNSRecord() = %q, want 10.0.0.4", host)
+	}
+
+	if err := c.DeleteDNSRecord(context.Background(), "svc-0.service.consul", "10.0.0.4", "svc-0"); err != nil {
+		t.Fatalf("DeleteDNSRecord error %s", err)
+	}
+	if _, err := c.GetDNSRecord(context.Background(), "svc-0.service.consul", "svc-0"); err == nil {
+		t.Fatal("GetDNSRecord() error = nil, want error after DeleteDNSRecord")
+	}
+}
+
+// TestConsulDNSReconcileReRegistersMissingMember asserts Reconcile
+// re-registers a member whose Consul service entry is missing, keyed by the
+// member's DNS name (the same id used by every other consulDNS method), not
+// its bare member name.
+func TestConsulDNSReconcileReRegistersMissingMember(t *testing.T) {
+	agent := newMemConsulAgent()
+	c := newConsulDNS(agent, 8080, "")
+
+	members := []MemberState{{ServiceName: "svc", MemberName: "svc-0", DNSName: "svc-0.service.consul", Host: "10.0.0.4"}}
+	if err := c.Reconcile(context.Background(), "cluster1", members); err != nil {
+		t.Fatalf("Reconcile error %s", err)
+	}
+
+	host, err := c.GetDNSRecord(context.Background(), "svc-0.service.consul", "svc-0")
+	if err != nil {
+		t.Fatalf("GetDNSRecord after Reconcile error %s", err)
+	}
+	if host != "10.0.0.4" {
+		t.Errorf("GetDNSRecord() after Reconcile = %q, want 10.0.0.4", host)
+	}
+}
+
+// TestNewConsulAgentFromConfigFailsClosed asserts the provider fails closed
+// with an error, rather than a nil agent, when no consul client is vendored
+// into the build.
+func TestNewConsulAgentFromConfigFailsClosed(t *testing.T) {
+	if _, err := newConsulAgentFromConfig(nil); err == nil {
+		t.Fatal("newConsulAgentFromConfig() error = nil, want error when no consul client is vendored")
+	}
+}
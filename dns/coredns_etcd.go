@@ -0,0 +1,98 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// skydnsRecord is the JSON value CoreDNS's etcd plugin expects at each
+// /skydns/<reversed-zone>/... key.
+type skydnsRecord struct {
+	Host string `json:"host"`
+	TTL  uint32 `json:"ttl,omitempty"`
+}
+
+// etcdKV is the minimal etcd client surface coreDNSEtcd needs. The
+// production constructor wires this to a clientv3.Client; tests can supply
+// an in-memory double.
+type etcdKV interface {
+	Put(ctx context.Context, key, val string) error
+	Get(ctx context.Context, key string) (val string, found bool, err error)
+	Delete(ctx context.Context, key string) error
+}
+
+// coreDNSEtcd registers A records for service members under CoreDNS's
+// skydns etcd key layout: /skydns/<zone-labels-reversed>/<name-labels-reversed>.
+type coreDNSEtcd struct {
+	kv  etcdKV
+	ttl uint32
+}
+
+func newCoreDNSEtcd(kv etcdKV, ttl uint32) *coreDNSEtcd {
+	if ttl == 0 {
+		ttl = 30
+	}
+	return &coreDNSEtcd{kv: kv, ttl: ttl}
+}
+
+func init() {
+	RegisterProvider("coredns-etcd", func(config map[string]string) (DNS, error) {
+		client, err := newEtcdClientFromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return newCoreDNSEtcd(client, 30), nil
+	})
+}
+
+// skydnsKey builds the reversed-label etcd key for name within zone, e.g.
+// name "member-0.service.firecamp.com" under zone "firecamp.com" becomes
+// /skydns/com/firecamp/service/member-0.
+func skydnsKey(name string) string {
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+	for i, j := 0, len(labels)-1; i < j; i, j = i+1, j-1 {
+		labels[i], labels[j] = labels[j], labels[i]
+	}
+	return "/skydns/" + strings.Join(labels, "/")
+}
+
+func (c *coreDNSEtcd) GetOrCreateHostedZoneIDByName(ctx context.Context, domain string, vpcID string, region string, private bool) (string, error) {
+	// CoreDNS/etcd has no hosted-zone concept; the zone is the domain itself.
+	return domain, nil
+}
+
+func (c *coreDNSEtcd) UpdateServiceDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error {
+	rec := skydnsRecord{Host: memberHost, TTL: c.ttl}
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		return err
+	}
+	return c.kv.Put(ctx, skydnsKey(dnsName), string(data))
+}
+
+func (c *coreDNSEtcd) DeleteDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error {
+	return c.kv.Delete(ctx, skydnsKey(dnsName))
+}
+
+func (c *coreDNSEtcd) GetDNSRecord(ctx context.Context, dnsName string, hostedZoneID string) (string, error) {
+	val, found, err := c.kv.Get(ctx, skydnsKey(dnsName))
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("dns: record %s not found", dnsName)
+	}
+	var rec skydnsRecord
+	if err := json.Unmarshal([]byte(val), &rec); err != nil {
+		return "", err
+	}
+	return rec.Host, nil
+}
+
+// newEtcdClientFromConfig is overridden in builds that vendor clientv3; the
+// default returns an error so the provider fails closed until wired up.
+var newEtcdClientFromConfig = func(config map[string]string) (etcdKV, error) {
+	return nil, fmt.Errorf("dns: coredns-etcd provider requires an etcd client, none configured")
+}
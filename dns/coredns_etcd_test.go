@@ -0,0 +1,73 @@
+package dns
+
+import (
+	"context"
+	"testing"
+)
+
+// memEtcdKV is an in-memory etcdKV double.
+type memEtcdKV struct {
+	data map[string]string
+}
+
+func newMemEtcdKV() *memEtcdKV {
+	return &memEtcdKV{data: make(map[string]string)}
+}
+
+func (kv *memEtcdKV) Put(ctx context.Context, key, val string) error {
+	kv.data[key] = val
+	return nil
+}
+
+func (kv *memEtcdKV) Get(ctx context.Context, key string) (string, bool, error) {
+	val, ok := kv.data[key]
+	return val, ok, nil
+}
+
+func (kv *memEtcdKV) Delete(ctx context.Context, key string) error {
+	delete(kv.data, key)
+	return nil
+}
+
+func TestSkydnsKeyReversesLabels(t *testing.T) {
+	got := skydnsKey("member-0.service.firecamp.com")
+	want := "/skydns/com/firecamp/service/member-0"
+	if got != want {
+		t.Errorf("skydnsKey() = %q, want %q", got, want)
+	}
+}
+
+// TestCoreDNSEtcdRegisterLookupDelete exercises the round trip a member
+// goes through against the skydns etcd key layout.
+func TestCoreDNSEtcdRegisterLookupDelete(t *testing.T) {
+	kv := newMemEtcdKV()
+	c := newCoreDNSEtcd(kv, 30)
+
+	if err := c.UpdateServiceDNSRecord(context.Background(), "member-0.service.firecamp.com", "10.0.0.4", ""); err != nil {
+		t.Fatalf("UpdateServiceDNSRecord error %s", err)
+	}
+
+	host, err := c.GetDNSRecord(context.Background(), "member-0.service.firecamp.com", "")
+	if err != nil {
+		t.Fatalf("GetDNSRecord error %s", err)
+	}
+	if host != "10.0.0.4" {
+		t.Errorf("GetDNSRecord() = %q, want 10.0.0.4", host)
+	}
+
+	if err := c.DeleteDNSRecord(context.Background(), "member-0.service.firecamp.com", "10.0.0.4", ""); err != nil {
+		t.Fatalf("DeleteDNSRecord error %s", err)
+	}
+	if _, err := c.GetDNSRecord(context.Background(), "member-0.service.firecamp.com", ""); err == nil {
+		t.Fatal("GetDNSRecord() error = nil, want error after DeleteDNSRecord")
+	}
+}
+
+// TestNewEtcdClientFromConfigFailsClosed asserts the provider fails closed
+// with an error, rather than a nil client, when no etcd client is vendored
+// into the build.
+func TestNewEtcdClientFromConfigFailsClosed(t *testing.T) {
+	if _, err := newEtcdClientFromConfig(nil); err == nil {
+		t.Fatal("newEtcdClientFromConfig() error = nil, want error when no etcd client is vendored")
+	}
+}
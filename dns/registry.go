@@ -0,0 +1,139 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DNS is the interface ManageService uses to register and look up service
+// member names. Existing callers (e.g. Route53) implement this directly;
+// Registry lets a ManageService be constructed with a provider selected by
+// name at runtime instead of a single hardcoded implementation.
+type DNS interface {
+	GetOrCreateHostedZoneIDByName(ctx context.Context, domain string, vpcID string, region string, private bool) (hostedZoneID string, err error)
+	UpdateServiceDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error
+	DeleteDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error
+	GetDNSRecord(ctx context.Context, dnsName string, hostedZoneID string) (host string, err error)
+}
+
+// Factory constructs a named DNS provider from its config blob. Providers
+// register a Factory at init time via RegisterProvider.
+type Factory func(config map[string]string) (DNS, error)
+
+// Registry holds named DNS provider factories and the live provider
+// instances ManageService has already constructed, so the same provider
+// instance is reused across services that request it.
+type Registry struct {
+	lock      sync.Mutex
+	factories map[string]Factory
+	instances map[string]DNS
+}
+
+// NewRegistry creates an empty Registry. Call RegisterProvider (or rely on
+// init-time registration from provider packages) before Get.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[string]Factory),
+		instances: make(map[string]DNS),
+	}
+}
+
+// defaultRegistry is populated by provider packages' init() funcs so
+// ManageService can be constructed with a provider name without importing
+// every provider package explicitly.
+var defaultRegistry = NewRegistry()
+
+// RegisterProvider registers a DNS provider factory under name on the
+// default Registry.
+func RegisterProvider(name string, f Factory) {
+	defaultRegistry.RegisterProvider(name, f)
+}
+
+// DefaultRegistry returns the process-wide Registry populated by provider
+// package init() funcs.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// RegisterProvider registers a DNS provider factory under name.
+func (r *Registry) RegisterProvider(name string, f Factory) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.factories[name] = f
+}
+
+// Get returns the named provider, constructing and caching it on first use
+// via its registered Factory and config.
+func (r *Registry) Get(name string, config map[string]string) (DNS, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if ins, ok := r.instances[name]; ok {
+		return ins, nil
+	}
+
+	f, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("dns: unknown provider %q", name)
+	}
+
+	ins, err := f(config)
+	if err != nil {
+		return nil, err
+	}
+	r.instances[name] = ins
+	return ins, nil
+}
+
+// ProviderConfig selects which registered DNS provider a given
+// CreateServiceRequest targets, plus the provider-specific config to
+// construct it with (e.g. etcd endpoints for coredns-etcd, or a Consul
+// agent address).
+type ProviderConfig struct {
+	Name   string
+	Config map[string]string
+}
+
+// MemberState is the subset of db.DB member state Reconcile compares
+// against each provider's own records to detect drift.
+type MemberState struct {
+	ServiceName string
+	MemberName  string
+	DNSName     string
+	Host        string
+}
+
+// Reconciler is implemented by providers that can detect and repair drift
+// between the authoritative member state in db.DB and their own records.
+// Not every provider needs this; Reconcile on Registry only calls it for
+// providers that opt in.
+type Reconciler interface {
+	Reconcile(ctx context.Context, cluster string, members []MemberState) error
+}
+
+// Reconcile walks every provider instance constructed by this Registry and,
+// for those implementing Reconciler, asks them to repair drift between
+// members (read from db.DB by the caller) and their own records. It is
+// meant to be called periodically by the manageserver; that caller is not
+// part of this tree, so Reconcile is exercised directly by this package's
+// tests instead.
+func (r *Registry) Reconcile(ctx context.Context, cluster string, members []MemberState) error {
+	r.lock.Lock()
+	instances := make([]DNS, 0, len(r.instances))
+	for _, ins := range r.instances {
+		instances = append(instances, ins)
+	}
+	r.lock.Unlock()
+
+	for _, ins := range instances {
+		rec, ok := ins.(Reconciler)
+		if !ok {
+			continue
+		}
+		if err := rec.Reconcile(ctx, cluster, members); err != nil {
+			return err
+		}
+	}
+	return nil
+}
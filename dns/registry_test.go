@@ -0,0 +1,116 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeDNS is a minimal DNS double used to test Registry without any real
+// provider wiring.
+type fakeDNS struct {
+	records map[string]string
+}
+
+func newFakeDNS() *fakeDNS {
+	return &fakeDNS{records: make(map[string]string)}
+}
+
+func (f *fakeDNS) GetOrCreateHostedZoneIDByName(ctx context.Context, domain string, vpcID string, region string, private bool) (string, error) {
+	return domain, nil
+}
+
+func (f *fakeDNS) UpdateServiceDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error {
+	f.records[dnsName] = memberHost
+	return nil
+}
+
+func (f *fakeDNS) DeleteDNSRecord(ctx context.Context, dnsName string, memberHost string, hostedZoneID string) error {
+	delete(f.records, dnsName)
+	return nil
+}
+
+func (f *fakeDNS) GetDNSRecord(ctx context.Context, dnsName string, hostedZoneID string) (string, error) {
+	host, ok := f.records[dnsName]
+	if !ok {
+		return "", errRecordNotFound
+	}
+	return host, nil
+}
+
+// errRecordNotFound is returned by fakeDNS.GetDNSRecord for a name that was
+// never registered.
+var errRecordNotFound = errors.New("dns: fake record not found")
+
+// TestRegistryGetConstructsOncePerName asserts Get calls the Factory once
+// per provider name and returns the cached instance on subsequent calls,
+// rather than reconstructing (and losing any state) every time.
+func TestRegistryGetConstructsOncePerName(t *testing.T) {
+	r := NewRegistry()
+	calls := 0
+	r.RegisterProvider("fake", func(config map[string]string) (DNS, error) {
+		calls++
+		return newFakeDNS(), nil
+	})
+
+	first, err := r.Get("fake", nil)
+	if err != nil {
+		t.Fatalf("Get() first call error %s", err)
+	}
+	second, err := r.Get("fake", nil)
+	if err != nil {
+		t.Fatalf("Get() second call error %s", err)
+	}
+	if first != second {
+		t.Fatal("Get() returned a different instance on the second call, want the cached one")
+	}
+	if calls != 1 {
+		t.Fatalf("factory called %d times, want 1", calls)
+	}
+}
+
+// TestRegistryGetUnknownProvider asserts Get reports an error rather than a
+// nil DNS for a name with no registered Factory.
+func TestRegistryGetUnknownProvider(t *testing.T) {
+	r := NewRegistry()
+	if _, err := r.Get("does-not-exist", nil); err == nil {
+		t.Fatal("Get() error = nil, want error for an unregistered provider name")
+	}
+}
+
+// fakeReconciler is a DNS provider double that records Reconcile calls.
+type fakeReconciler struct {
+	fakeDNS
+	reconciled []MemberState
+}
+
+func (f *fakeReconciler) Reconcile(ctx context.Context, cluster string, members []MemberState) error {
+	f.reconciled = append(f.reconciled, members...)
+	return nil
+}
+
+// TestRegistryReconcileOnlyCallsOptedInProviders asserts Reconcile calls
+// Reconcile on constructed instances that implement Reconciler and skips
+// those that don't, instead of panicking on a failed type assertion.
+func TestRegistryReconcileOnlyCallsOptedInProviders(t *testing.T) {
+	r := NewRegistry()
+	reconciler := &fakeReconciler{fakeDNS: *newFakeDNS()}
+	r.RegisterProvider("reconciler", func(config map[string]string) (DNS, error) { return reconciler, nil })
+	r.RegisterProvider("plain", func(config map[string]string) (DNS, error) { return newFakeDNS(), nil })
+
+	if _, err := r.Get("reconciler", nil); err != nil {
+		t.Fatalf("Get(reconciler) error %s", err)
+	}
+	if _, err := r.Get("plain", nil); err != nil {
+		t.Fatalf("Get(plain) error %s", err)
+	}
+
+	members := []MemberState{{ServiceName: "svc", MemberName: "svc-0", DNSName: "svc-0.example.com", Host: "10.0.0.4"}}
+	if err := r.Reconcile(context.Background(), "cluster1", members); err != nil {
+		t.Fatalf("Reconcile() error %s", err)
+	}
+
+	if len(reconciler.reconciled) != 1 || reconciler.reconciled[0].MemberName != "svc-0" {
+		t.Fatalf("reconciled = %v, want the one member passed in", reconciler.reconciled)
+	}
+}
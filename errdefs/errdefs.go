@@ -0,0 +1,203 @@
+// Package errdefs defines the canonical error kinds shared across
+// ControlDBServer and its clients, so a gRPC boundary doesn't force callers
+// back onto string-matching codes.Unknown. A server-side handler returns an
+// *errdefs.Error (or wraps one with fmt.Errorf's %w); ToGRPC turns it into a
+// grpc/status error carrying structured details, and FromGRPC on the client
+// side turns that back into an *errdefs.Error an errors.As caller can
+// inspect.
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is one of the canonical error categories ControlDBServer reports.
+// It intentionally mirrors the handful of codes.Code values the server
+// actually has occasion to return, rather than the full gRPC code space.
+type Kind int
+
+const (
+	KindUnknown Kind = iota
+	KindNotFound
+	KindAlreadyExists
+	KindInvalidArgument
+	KindFailedPrecondition
+	KindUnavailable
+	KindDeadlineExceeded
+	KindPermissionDenied
+	KindInternal
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "NotFound"
+	case KindAlreadyExists:
+		return "AlreadyExists"
+	case KindInvalidArgument:
+		return "InvalidArgument"
+	case KindFailedPrecondition:
+		return "FailedPrecondition"
+	case KindUnavailable:
+		return "Unavailable"
+	case KindDeadlineExceeded:
+		return "DeadlineExceeded"
+	case KindPermissionDenied:
+		return "PermissionDenied"
+	case KindInternal:
+		return "Internal"
+	default:
+		return "Unknown"
+	}
+}
+
+// Error is the typed error carried across the ControlDBServer/client
+// boundary. The optional ServiceUUID/FileID/RequestUUID fields become a
+// ResourceInfo/ErrorInfo detail on the wire; RetryAfterSeconds becomes a
+// RetryInfo detail when set.
+type Error struct {
+	Kind    Kind
+	Message string
+	Cause   error
+
+	RequestUUID       string
+	ServiceUUID       string
+	FileID            string
+	RetryAfterSeconds int32
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Kind, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+}
+
+// Unwrap lets errors.Is/errors.As see through to Cause.
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+func newf(kind Kind, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+// NotFound builds a KindNotFound error, e.g. for a GetServiceAttr miss.
+func NotFound(format string, args ...interface{}) *Error {
+	return newf(KindNotFound, format, args...)
+}
+
+// AlreadyExists builds a KindAlreadyExists error, e.g. for a CreateService
+// CAS failure against an existing row.
+func AlreadyExists(format string, args ...interface{}) *Error {
+	return newf(KindAlreadyExists, format, args...)
+}
+
+// InvalidArgument builds a KindInvalidArgument error for a malformed
+// request.
+func InvalidArgument(format string, args ...interface{}) *Error {
+	return newf(KindInvalidArgument, format, args...)
+}
+
+// FailedPrecondition builds a KindFailedPrecondition error, e.g. an
+// UpdateServiceAttr whose ExpectedRevision is stale.
+func FailedPrecondition(format string, args ...interface{}) *Error {
+	return newf(KindFailedPrecondition, format, args...)
+}
+
+// Unavailable builds a KindUnavailable error for a transient backend
+// failure that is safe to retry.
+func Unavailable(format string, args ...interface{}) *Error {
+	return newf(KindUnavailable, format, args...)
+}
+
+// DeadlineExceeded builds a KindDeadlineExceeded error.
+func DeadlineExceeded(format string, args ...interface{}) *Error {
+	return newf(KindDeadlineExceeded, format, args...)
+}
+
+// PermissionDenied builds a KindPermissionDenied error.
+func PermissionDenied(format string, args ...interface{}) *Error {
+	return newf(KindPermissionDenied, format, args...)
+}
+
+// Internal builds a KindInternal error for anything that doesn't fit one of
+// the above and shouldn't be retried blindly.
+func Internal(format string, args ...interface{}) *Error {
+	return newf(KindInternal, format, args...)
+}
+
+// WithCause attaches the underlying error this one wraps, preserved across
+// ToGRPC/FromGRPC as a DebugInfo detail.
+func (e *Error) WithCause(cause error) *Error {
+	e.Cause = cause
+	return e
+}
+
+// WithRequestUUID attaches the request's trace id.
+func (e *Error) WithRequestUUID(requuid string) *Error {
+	e.RequestUUID = requuid
+	return e
+}
+
+// WithResource attaches the service/file the error is about.
+func (e *Error) WithResource(serviceUUID, fileID string) *Error {
+	e.ServiceUUID = serviceUUID
+	e.FileID = fileID
+	return e
+}
+
+// WithRetryAfter marks the error as retryable after the given number of
+// seconds, surfaced to the client as a RetryInfo detail.
+func (e *Error) WithRetryAfter(seconds int32) *Error {
+	e.RetryAfterSeconds = seconds
+	return e
+}
+
+func kindOf(err error) (Kind, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind, true
+	}
+	return KindUnknown, false
+}
+
+// IsNotFound reports whether err is, or wraps, a KindNotFound error.
+func IsNotFound(err error) bool { k, ok := kindOf(err); return ok && k == KindNotFound }
+
+// IsAlreadyExists reports whether err is, or wraps, a KindAlreadyExists error.
+func IsAlreadyExists(err error) bool { k, ok := kindOf(err); return ok && k == KindAlreadyExists }
+
+// IsInvalidArgument reports whether err is, or wraps, a KindInvalidArgument error.
+func IsInvalidArgument(err error) bool {
+	k, ok := kindOf(err)
+	return ok && k == KindInvalidArgument
+}
+
+// IsFailedPrecondition reports whether err is, or wraps, a
+// KindFailedPrecondition error.
+func IsFailedPrecondition(err error) bool {
+	k, ok := kindOf(err)
+	return ok && k == KindFailedPrecondition
+}
+
+// IsUnavailable reports whether err is, or wraps, a KindUnavailable error.
+func IsUnavailable(err error) bool { k, ok := kindOf(err); return ok && k == KindUnavailable }
+
+// IsDeadlineExceeded reports whether err is, or wraps, a
+// KindDeadlineExceeded error.
+func IsDeadlineExceeded(err error) bool {
+	k, ok := kindOf(err)
+	return ok && k == KindDeadlineExceeded
+}
+
+// IsPermissionDenied reports whether err is, or wraps, a
+// KindPermissionDenied error.
+func IsPermissionDenied(err error) bool {
+	k, ok := kindOf(err)
+	return ok && k == KindPermissionDenied
+}
+
+// IsInternal reports whether err is, or wraps, a KindInternal error.
+func IsInternal(err error) bool { k, ok := kindOf(err); return ok && k == KindInternal }
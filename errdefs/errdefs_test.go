@@ -0,0 +1,120 @@
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorErrorString(t *testing.T) {
+	e := NotFound("service %s", "uuid-0")
+	if got, want := e.Error(), "NotFound: service uuid-0"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	wrapped := e.WithCause(errors.New("boom"))
+	if got, want := wrapped.Error(), "NotFound: service uuid-0: boom"; got != want {
+		t.Errorf("Error() with cause = %q, want %q", got, want)
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	cause := errors.New("boom")
+	e := Internal("write failed").WithCause(cause)
+
+	if got := errors.Unwrap(e); got != cause {
+		t.Errorf("Unwrap() = %v, want %v", got, cause)
+	}
+	if !errors.Is(e, cause) {
+		t.Error("errors.Is(e, cause) = false, want true")
+	}
+}
+
+func TestWithResourceAndRetryAfter(t *testing.T) {
+	e := Unavailable("backend down").
+		WithRequestUUID("req-1").
+		WithResource("service-uuid", "file-uuid").
+		WithRetryAfter(5)
+
+	if e.RequestUUID != "req-1" {
+		t.Errorf("RequestUUID = %q, want %q", e.RequestUUID, "req-1")
+	}
+	if e.ServiceUUID != "service-uuid" || e.FileID != "file-uuid" {
+		t.Errorf("ServiceUUID/FileID = %q/%q, want %q/%q", e.ServiceUUID, e.FileID, "service-uuid", "file-uuid")
+	}
+	if e.RetryAfterSeconds != 5 {
+		t.Errorf("RetryAfterSeconds = %d, want 5", e.RetryAfterSeconds)
+	}
+}
+
+// TestIsKind asserts every constructor/Is* pair agrees with each other, and
+// that each Is* only matches its own Kind, including through fmt.Errorf's
+// %w wrapping.
+func TestIsKind(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func() *Error
+		is    func(error) bool
+	}{
+		{"NotFound", func() *Error { return NotFound("x") }, IsNotFound},
+		{"AlreadyExists", func() *Error { return AlreadyExists("x") }, IsAlreadyExists},
+		{"InvalidArgument", func() *Error { return InvalidArgument("x") }, IsInvalidArgument},
+		{"FailedPrecondition", func() *Error { return FailedPrecondition("x") }, IsFailedPrecondition},
+		{"Unavailable", func() *Error { return Unavailable("x") }, IsUnavailable},
+		{"DeadlineExceeded", func() *Error { return DeadlineExceeded("x") }, IsDeadlineExceeded},
+		{"PermissionDenied", func() *Error { return PermissionDenied("x") }, IsPermissionDenied},
+		{"Internal", func() *Error { return Internal("x") }, IsInternal},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.build()
+			if !tc.is(err) {
+				t.Errorf("Is%s(%v) = false, want true", tc.name, err)
+			}
+			if !tc.is(fmt.Errorf("context: %w", err)) {
+				t.Errorf("Is%s(wrapped) = false, want true", tc.name)
+			}
+
+			for _, other := range tests {
+				if other.name == tc.name {
+					continue
+				}
+				if tc.is(other.build()) {
+					t.Errorf("Is%s(%s) = true, want false", tc.name, other.name)
+				}
+			}
+		})
+	}
+}
+
+func TestIsKindFalseForPlainError(t *testing.T) {
+	if IsNotFound(errors.New("plain")) {
+		t.Error("IsNotFound(plain error) = true, want false")
+	}
+}
+
+func TestKindString(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		want string
+	}{
+		{KindUnknown, "Unknown"},
+		{KindNotFound, "NotFound"},
+		{KindAlreadyExists, "AlreadyExists"},
+		{KindInvalidArgument, "InvalidArgument"},
+		{KindFailedPrecondition, "FailedPrecondition"},
+		{KindUnavailable, "Unavailable"},
+		{KindDeadlineExceeded, "DeadlineExceeded"},
+		{KindPermissionDenied, "PermissionDenied"},
+		{KindInternal, "Internal"},
+		{Kind(99), "Unknown"},
+	}
+
+	for _, tc := range tests {
+		if got := tc.kind.String(); got != tc.want {
+			t.Errorf("Kind(%d).String() = %q, want %q", tc.kind, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package errdefs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var kindToCode = map[Kind]codes.Code{
+	KindNotFound:           codes.NotFound,
+	KindAlreadyExists:      codes.AlreadyExists,
+	KindInvalidArgument:    codes.InvalidArgument,
+	KindFailedPrecondition: codes.FailedPrecondition,
+	KindUnavailable:        codes.Unavailable,
+	KindDeadlineExceeded:   codes.DeadlineExceeded,
+	KindPermissionDenied:   codes.PermissionDenied,
+	KindInternal:           codes.Internal,
+}
+
+var codeToKind = map[codes.Code]Kind{
+	codes.NotFound:           KindNotFound,
+	codes.AlreadyExists:      KindAlreadyExists,
+	codes.InvalidArgument:    KindInvalidArgument,
+	codes.FailedPrecondition: KindFailedPrecondition,
+	codes.Unavailable:        KindUnavailable,
+	codes.DeadlineExceeded:   KindDeadlineExceeded,
+	codes.PermissionDenied:   KindPermissionDenied,
+	codes.Internal:           KindInternal,
+}
+
+// ToGRPC converts err into a grpc/status error for the wire. A non-*Error
+// (e.g. a plain error returned by application code that hasn't been
+// migrated yet) becomes codes.Unknown, same as grpc's default behavior
+// today, so this is a drop-in replacement at call sites that return err
+// directly from a handler.
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		return status.Error(codes.Unknown, err.Error())
+	}
+
+	code, ok := kindToCode[e.Kind]
+	if !ok {
+		code = codes.Unknown
+	}
+	st := status.New(code, e.Message)
+
+	var details []proto.Message
+	if e.RequestUUID != "" {
+		details = append(details, &errdetails.ErrorInfo{
+			Reason:   e.Kind.String(),
+			Metadata: map[string]string{"requestUUID": e.RequestUUID},
+		})
+	}
+	if e.ServiceUUID != "" || e.FileID != "" {
+		details = append(details, &errdetails.ResourceInfo{
+			ResourceType: "controldb",
+			ResourceName: e.ServiceUUID,
+			Description:  e.FileID,
+		})
+	}
+	if e.RetryAfterSeconds > 0 {
+		details = append(details, &errdetails.RetryInfo{
+			RetryDelay: ptypes.DurationProto(time.Duration(e.RetryAfterSeconds) * time.Second),
+		})
+	}
+	if e.Cause != nil {
+		details = append(details, &errdetails.DebugInfo{Detail: e.Cause.Error()})
+	}
+
+	if len(details) == 0 {
+		return st.Err()
+	}
+
+	stWithDetails, detailErr := st.WithDetails(details...)
+	if detailErr != nil {
+		// attaching details is best-effort; the bare status is still useful.
+		return st.Err()
+	}
+	return stWithDetails.Err()
+}
+
+// FromGRPC reconstructs a typed *Error from a grpc/status error received
+// over the wire, preserving whatever ErrorInfo/ResourceInfo/RetryInfo/
+// DebugInfo details ToGRPC attached. A status this package didn't produce
+// (no recognized code, or not a status error at all) is returned unchanged,
+// so existing grpc.Code(err) checks keep working during migration.
+func FromGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	kind, ok := codeToKind[st.Code()]
+	if !ok {
+		return err
+	}
+
+	e := &Error{Kind: kind, Message: st.Message()}
+
+	for _, d := range st.Details() {
+		switch detail := d.(type) {
+		case *errdetails.ErrorInfo:
+			e.RequestUUID = detail.Metadata["requestUUID"]
+		case *errdetails.ResourceInfo:
+			e.ServiceUUID = detail.ResourceName
+			e.FileID = detail.Description
+		case *errdetails.RetryInfo:
+			if dur, durErr := ptypes.Duration(detail.RetryDelay); durErr == nil {
+				e.RetryAfterSeconds = int32(dur.Seconds())
+			}
+		case *errdetails.DebugInfo:
+			e.Cause = causeError(detail.Detail)
+		}
+	}
+
+	return e
+}
+
+// causeError is the simplest possible error wrapping a reconstructed
+// DebugInfo message, so e.Cause is non-nil and e.Error() reports the
+// original server-side cause text after a round trip.
+type causeError string
+
+func (c causeError) Error() string { return string(c) }
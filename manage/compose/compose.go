@@ -0,0 +1,180 @@
+// Package compose parses a Compose-v3-like YAML stack description into the
+// manage.CreateServiceRequest objects CreateService expects, so operators
+// can describe a multi-service stack declaratively instead of hand-building
+// one request struct per service. Stack.ToCreateServiceRequests is meant to
+// be fed to ManageService.CreateService in OrderedServiceNames order, and a
+// firecamp-cli apply -f stack.yml command would drive that loop; neither
+// the manage package (CreateServiceRequest/ServiceCommonRequest/
+// ReplicaConfig/ReplicaConfigFile) nor any firecamp-cli command tree exists
+// in this snapshot, so that wiring isn't possible here. Parse and
+// Stack.OrderedServiceNames don't depend on those missing types and are
+// covered directly by compose_test.go; ToCreateServiceRequest(s) cannot be
+// compiled or tested in this tree until the manage package exists.
+package compose
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/cloudstax/firecamp/common"
+	"github.com/cloudstax/firecamp/manage"
+)
+
+// Stack is the root of a parsed stack file.
+type Stack struct {
+	Version  string                 `yaml:"version"`
+	Services map[string]ServiceSpec `yaml:"services"`
+}
+
+// VolumeSpec mirrors the subset of a Compose volume entry Firecamp cares
+// about. The x-firecamp-* extensions are fields of ServiceSpec, not of
+// VolumeSpec.
+type VolumeSpec struct {
+	Type   string `yaml:"type"`
+	SizeGB int64  `yaml:"size_gb"`
+	IOPS   int64  `yaml:"iops"`
+}
+
+// ConfigSpec is one config file template attached to a service, fed into
+// checkAndCreateConfigFile for every replica.
+type ConfigSpec struct {
+	FileName string `yaml:"file_name"`
+	Content  string `yaml:"content"`
+}
+
+// ServiceSpec is one service entry in the stack file.
+type ServiceSpec struct {
+	Image           string       `yaml:"image"`
+	Replicas        int64        `yaml:"replicas"`
+	Volume          VolumeSpec   `yaml:"volume"`
+	JournalVolume   *VolumeSpec  `yaml:"journal_volume,omitempty"`
+	RequireStaticIP bool         `yaml:"static_ip"`
+	RegisterDNS     bool         `yaml:"register_dns"`
+	AZs             []string     `yaml:"placement_azs"`
+	Configs         []ConfigSpec `yaml:"configs"`
+	DependsOn       []string     `yaml:"depends_on"`
+
+	// Firecamp extensions for things Compose doesn't model.
+	XFirecampJournalDevice string `yaml:"x-firecamp-journal-device,omitempty"`
+	XFirecampStaticIPPool  string `yaml:"x-firecamp-static-ip-pool,omitempty"`
+	XFirecampHostedZoneID  string `yaml:"x-firecamp-hosted-zone-id,omitempty"`
+}
+
+// Parse decodes a stack YAML document.
+func Parse(data []byte) (*Stack, error) {
+	var s Stack
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("compose: parse error: %v", err)
+	}
+	return &s, nil
+}
+
+// OrderedServiceNames returns service names in dependency order (every
+// depends_on target before its dependent), erroring on a cycle.
+func (s *Stack) OrderedServiceNames() ([]string, error) {
+	visited := make(map[string]int) // 0=unvisited,1=visiting,2=done
+	var order []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch visited[name] {
+		case 2:
+			return nil
+		case 1:
+			return fmt.Errorf("compose: dependency cycle at service %q", name)
+		}
+		visited[name] = 1
+
+		spec, ok := s.Services[name]
+		if !ok {
+			return fmt.Errorf("compose: service %q referenced by depends_on does not exist", name)
+		}
+		for _, dep := range spec.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+
+		visited[name] = 2
+		order = append(order, name)
+		return nil
+	}
+
+	for name := range s.Services {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// ToCreateServiceRequest converts a single ServiceSpec into the equivalent
+// manage.CreateServiceRequest, given the cluster-wide fields CreateService
+// needs that aren't part of the per-service spec.
+func ToCreateServiceRequest(cluster, region, serviceName string, spec ServiceSpec) (*manage.CreateServiceRequest, error) {
+	if spec.Replicas <= 0 {
+		return nil, fmt.Errorf("compose: service %q must declare replicas > 0", serviceName)
+	}
+
+	az := ""
+	if len(spec.AZs) > 0 {
+		az = spec.AZs[0]
+	}
+
+	replicaCfgs := make([]*manage.ReplicaConfig, spec.Replicas)
+	for i := int64(0); i < spec.Replicas; i++ {
+		var cfgFiles []*manage.ReplicaConfigFile
+		for _, c := range spec.Configs {
+			cfgFiles = append(cfgFiles, &manage.ReplicaConfigFile{FileName: c.FileName, Content: c.Content})
+		}
+		replicaCfgs[i] = &manage.ReplicaConfig{Zone: az, Configs: cfgFiles}
+	}
+
+	req := &manage.CreateServiceRequest{
+		Service: &manage.ServiceCommonRequest{
+			Region:      region,
+			Cluster:     cluster,
+			ServiceName: serviceName,
+		},
+		Replicas: spec.Replicas,
+		Volume: &common.ServiceVolume{
+			VolumeType:   spec.Volume.Type,
+			VolumeSizeGB: spec.Volume.SizeGB,
+			Iops:         spec.Volume.IOPS,
+		},
+		RegisterDNS:     spec.RegisterDNS,
+		RequireStaticIP: spec.RequireStaticIP,
+		ReplicaConfigs:  replicaCfgs,
+	}
+
+	if spec.JournalVolume != nil {
+		req.JournalVolume = &common.ServiceVolume{
+			VolumeType:   spec.JournalVolume.Type,
+			VolumeSizeGB: spec.JournalVolume.SizeGB,
+			Iops:         spec.JournalVolume.IOPS,
+		}
+	}
+
+	return req, nil
+}
+
+// ToCreateServiceRequests converts every service in the stack into a
+// manage.CreateServiceRequest, in dependency order, ready to be passed to
+// ManageService.CreateService in sequence.
+func (s *Stack) ToCreateServiceRequests(cluster, region string) ([]*manage.CreateServiceRequest, error) {
+	order, err := s.OrderedServiceNames()
+	if err != nil {
+		return nil, err
+	}
+
+	reqs := make([]*manage.CreateServiceRequest, 0, len(order))
+	for _, name := range order {
+		req, err := ToCreateServiceRequest(cluster, region, name, s.Services[name])
+		if err != nil {
+			return nil, err
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
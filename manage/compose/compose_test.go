@@ -0,0 +1,103 @@
+package compose
+
+import "testing"
+
+const sampleStack = `
+version: "1"
+services:
+  web:
+    image: nginx
+    replicas: 2
+    volume:
+      type: gp2
+      size_gb: 10
+    depends_on: ["db"]
+  db:
+    image: mongo
+    replicas: 3
+    volume:
+      type: gp2
+      size_gb: 100
+`
+
+func TestParse(t *testing.T) {
+	s, err := Parse([]byte(sampleStack))
+	if err != nil {
+		t.Fatalf("Parse() error %s", err)
+	}
+	if s.Version != "1" {
+		t.Errorf("Version = %q, want 1", s.Version)
+	}
+	if len(s.Services) != 2 {
+		t.Fatalf("len(Services) = %d, want 2", len(s.Services))
+	}
+	web, ok := s.Services["web"]
+	if !ok {
+		t.Fatal(`Services["web"] missing`)
+	}
+	if web.Replicas != 2 || web.Volume.SizeGB != 10 {
+		t.Errorf("web = %+v, want Replicas 2, Volume.SizeGB 10", web)
+	}
+}
+
+func TestParseInvalidYAML(t *testing.T) {
+	if _, err := Parse([]byte("not: [valid: yaml")); err == nil {
+		t.Fatal("Parse() error = nil, want error for malformed YAML")
+	}
+}
+
+// TestOrderedServiceNamesRespectsDependsOn asserts every service appears
+// after every service it depends_on, e.g. "web" (which depends_on "db")
+// never comes before "db".
+func TestOrderedServiceNamesRespectsDependsOn(t *testing.T) {
+	s, err := Parse([]byte(sampleStack))
+	if err != nil {
+		t.Fatalf("Parse() error %s", err)
+	}
+
+	order, err := s.OrderedServiceNames()
+	if err != nil {
+		t.Fatalf("OrderedServiceNames() error %s", err)
+	}
+	if len(order) != 2 {
+		t.Fatalf("OrderedServiceNames() = %v, want 2 entries", order)
+	}
+
+	pos := make(map[string]int, len(order))
+	for i, name := range order {
+		pos[name] = i
+	}
+	if pos["db"] >= pos["web"] {
+		t.Errorf("order = %v, want db before web (web depends_on db)", order)
+	}
+}
+
+// TestOrderedServiceNamesDetectsCycle asserts a dependency cycle is reported
+// as an error instead of recursing forever or silently dropping a service.
+func TestOrderedServiceNamesDetectsCycle(t *testing.T) {
+	s := &Stack{
+		Services: map[string]ServiceSpec{
+			"a": {Replicas: 1, DependsOn: []string{"b"}},
+			"b": {Replicas: 1, DependsOn: []string{"a"}},
+		},
+	}
+
+	if _, err := s.OrderedServiceNames(); err == nil {
+		t.Fatal("OrderedServiceNames() error = nil, want error for a dependency cycle")
+	}
+}
+
+// TestOrderedServiceNamesMissingDependency asserts a depends_on target that
+// doesn't exist in the stack is reported as an error rather than silently
+// skipped.
+func TestOrderedServiceNamesMissingDependency(t *testing.T) {
+	s := &Stack{
+		Services: map[string]ServiceSpec{
+			"web": {Replicas: 1, DependsOn: []string{"does-not-exist"}},
+		},
+	}
+
+	if _, err := s.OrderedServiceNames(); err == nil {
+		t.Fatal("OrderedServiceNames() error = nil, want error for a missing depends_on target")
+	}
+}
@@ -0,0 +1,252 @@
+// Package deploy implements the rolling-update and blue/green rollout
+// strategies intended to back a ManageService's UpdateService,
+// RollingRestart, and BlueGreenSwitch methods. RollingUpdater and
+// BlueGreenSwitcher are driven entirely by caller-supplied closures (apply,
+// provisionGreen, flipDNS, gcBlue) and a db.DeploymentDB, so they don't
+// depend on ManageService directly; that caller, which would supply those
+// closures from its own member/volume/DNS plumbing, is not part of this
+// tree. TestUtil_RollingUpdate and TestUtil_BlueGreenSwitch exercise both
+// types directly against fakes instead.
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cloudstax/firecamp/db"
+)
+
+// StrategyKind selects how UpdateService rolls out a change.
+type StrategyKind string
+
+const (
+	StrategyRecreate      StrategyKind = "Recreate"
+	StrategyRollingUpdate StrategyKind = "RollingUpdate"
+	StrategyBlueGreen     StrategyKind = "BlueGreen"
+)
+
+// RollingUpdateOptions configures StrategyRollingUpdate.
+type RollingUpdateOptions struct {
+	MaxUnavailable  int
+	MaxSurge        int
+	MinReadySeconds int
+}
+
+// BlueGreenOptions configures StrategyBlueGreen.
+type BlueGreenOptions struct {
+	VerificationTimeout time.Duration
+}
+
+// Strategy is the update strategy requested on an UpdateServiceRequest.
+type Strategy struct {
+	Kind      StrategyKind
+	Rolling   RollingUpdateOptions
+	BlueGreen BlueGreenOptions
+}
+
+// Member is the subset of common.ServiceMember the planner needs to compute
+// a deterministic, AZ-spread-aware rollout order.
+type Member struct {
+	Name  string
+	Index int64
+	Zone  string
+}
+
+// HealthChecker is called between rollout steps so a member is only
+// considered done once it reports healthy again. Implementations typically
+// poll the service's own health endpoint.
+type HealthChecker interface {
+	IsHealthy(ctx context.Context, member string) (bool, error)
+}
+
+// PlanRollingOrder returns members interleaved across zones: each round
+// takes the next member by index from every zone, in zone-name order, which
+// keeps simultaneously-draining members spread across AZs. Once a zone has
+// no members left, the remaining members of the other zones follow each
+// other, so with uneven zone sizes consecutive members can share a zone.
+func PlanRollingOrder(members []Member) []string {
+	byZone := make(map[string][]Member)
+	var zones []string
+	for _, m := range members {
+		if _, ok := byZone[m.Zone]; !ok {
+			zones = append(zones, m.Zone)
+		}
+		byZone[m.Zone] = append(byZone[m.Zone], m)
+	}
+	sort.Strings(zones)
+	for _, zone := range zones {
+		ms := byZone[zone]
+		sort.Slice(ms, func(i, j int) bool { return ms[i].Index < ms[j].Index })
+		byZone[zone] = ms
+	}
+
+	var order []string
+	for i := 0; ; i++ {
+		appended := false
+		for _, zone := range zones {
+			ms := byZone[zone]
+			if i < len(ms) {
+				order = append(order, ms[i].Name)
+				appended = true
+			}
+		}
+		if !appended {
+			break
+		}
+	}
+	return order
+}
+
+// RollingUpdater drives a rolling update step by step, recording progress in
+// a db.Deployment row so a restart can resume via Resume instead of starting
+// over at member 0.
+type RollingUpdater struct {
+	deployDB db.DeploymentDB
+	checker  HealthChecker
+	apply    func(ctx context.Context, member string) error
+}
+
+// NewRollingUpdater creates a RollingUpdater. apply performs the actual
+// member update (new image, config, or in-place volume resize); checker
+// gates advancing to the next member.
+func NewRollingUpdater(deployDB db.DeploymentDB, checker HealthChecker, apply func(ctx context.Context, member string) error) *RollingUpdater {
+	return &RollingUpdater{deployDB: deployDB, checker: checker, apply: apply}
+}
+
+// Start begins a new rolling update for serviceUUID over members, persisting
+// the computed order before applying the first step.
+func (u *RollingUpdater) Start(ctx context.Context, serviceUUID string, members []Member, opts RollingUpdateOptions) error {
+	d := &db.Deployment{
+		ServiceUUID: serviceUUID,
+		Type:        db.DeploymentTypeRollingUpdate,
+		Status:      db.DeploymentStatusInProgress,
+		MemberOrder: PlanRollingOrder(members),
+		StartedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := u.deployDB.CreateDeployment(d); err != nil {
+		return err
+	}
+	return u.Resume(ctx, serviceUUID, opts)
+}
+
+// Resume continues a rolling update from the Deployment row's recorded
+// progress, so a process restart mid-rollout picks up where it left off.
+func (u *RollingUpdater) Resume(ctx context.Context, serviceUUID string, opts RollingUpdateOptions) error {
+	d, err := u.deployDB.GetDeployment(serviceUUID)
+	if err != nil {
+		return err
+	}
+	if d.Status != db.DeploymentStatusInProgress {
+		return nil
+	}
+
+	for {
+		member := d.NextMember()
+		if member == "" {
+			break
+		}
+
+		if err := u.apply(ctx, member); err != nil {
+			d.Status = db.DeploymentStatusFailed
+			u.deployDB.UpdateDeployment(d)
+			return fmt.Errorf("deploy: update member %s: %v", member, err)
+		}
+
+		if opts.MinReadySeconds > 0 {
+			time.Sleep(time.Duration(opts.MinReadySeconds) * time.Second)
+		}
+
+		if u.checker != nil {
+			healthy, err := u.checker.IsHealthy(ctx, member)
+			if err != nil || !healthy {
+				d.Status = db.DeploymentStatusFailed
+				u.deployDB.UpdateDeployment(d)
+				return fmt.Errorf("deploy: member %s unhealthy after update: %v", member, err)
+			}
+		}
+
+		d.MarkMemberDone(member)
+		if err := u.deployDB.UpdateDeployment(d); err != nil {
+			return err
+		}
+	}
+
+	d.Status = db.DeploymentStatusCompleted
+	return u.deployDB.UpdateDeployment(d)
+}
+
+// BlueGreenSwitcher provisions a parallel "-green" member set, flips DNS to
+// it once healthy, and garbage-collects the old "blue" set after the
+// verification window.
+type BlueGreenSwitcher struct {
+	deployDB       db.DeploymentDB
+	provisionGreen func(ctx context.Context) error
+	flipDNS        func(ctx context.Context) error
+	gcBlue         func(ctx context.Context) error
+	checker        HealthChecker
+}
+
+// NewBlueGreenSwitcher creates a BlueGreenSwitcher from the three
+// caller-supplied phases, each delegating to the manage service's existing
+// member/volume/DNS plumbing.
+func NewBlueGreenSwitcher(deployDB db.DeploymentDB, checker HealthChecker,
+	provisionGreen, flipDNS, gcBlue func(ctx context.Context) error) *BlueGreenSwitcher {
+	return &BlueGreenSwitcher{
+		deployDB:       deployDB,
+		checker:        checker,
+		provisionGreen: provisionGreen,
+		flipDNS:        flipDNS,
+		gcBlue:         gcBlue,
+	}
+}
+
+// Switch runs the blue/green rollout: provision green, wait for health
+// within the verification timeout, flip DNS, then garbage-collect blue.
+func (b *BlueGreenSwitcher) Switch(ctx context.Context, serviceUUID string, opts BlueGreenOptions) error {
+	d := &db.Deployment{
+		ServiceUUID:       serviceUUID,
+		Type:              db.DeploymentTypeBlueGreen,
+		Status:            db.DeploymentStatusInProgress,
+		GreenMemberSuffix: "-green",
+		StartedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
+	}
+	if err := b.deployDB.CreateDeployment(d); err != nil {
+		return err
+	}
+
+	if err := b.provisionGreen(ctx); err != nil {
+		d.Status = db.DeploymentStatusFailed
+		b.deployDB.UpdateDeployment(d)
+		return err
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, opts.VerificationTimeout)
+	defer cancel()
+	if b.checker != nil {
+		healthy, err := b.checker.IsHealthy(timeoutCtx, serviceUUID+d.GreenMemberSuffix)
+		if err != nil || !healthy {
+			d.Status = db.DeploymentStatusFailed
+			b.deployDB.UpdateDeployment(d)
+			return fmt.Errorf("deploy: green set failed verification: %v", err)
+		}
+	}
+
+	if err := b.flipDNS(ctx); err != nil {
+		d.Status = db.DeploymentStatusFailed
+		b.deployDB.UpdateDeployment(d)
+		return err
+	}
+
+	if err := b.gcBlue(ctx); err != nil {
+		// DNS already points at green; leaving blue around is safe, surface
+		// the GC failure for the operator to retry/cleanup manually.
+		return err
+	}
+
+	d.Status = db.DeploymentStatusCompleted
+	return b.deployDB.UpdateDeployment(d)
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// VolumeTopology describes the devices already attached to a host, so a
+// DevicePlacer/extender can avoid name collisions and respect journal vs.
+// primary role placement.
+type VolumeTopology struct {
+	HostID       string
+	ExistingDevs []string
+}
+
+// DeviceRequest describes one device allocation request, e.g. the primary
+// or journal volume for a replica.
+type DeviceRequest struct {
+	ClusterName string
+	ServiceName string
+	MemberName  string
+	// Role is "primary" or "journal".
+	Role       string
+	SizeGB     int64
+	IOPS       int64
+	VolumeType string
+	AZ         string
+}
+
+// DevicePlacer chooses a device name and, when an extender is configured,
+// lets the extender override the candidate list or the final choice before
+// createDevice/ScheduleService is called.
+type DevicePlacer interface {
+	// PlaceDevice returns the device name to use for req on the given host
+	// topology. candidates is the set of device names the local allocator
+	// would otherwise try, in preference order.
+	PlaceDevice(req *DeviceRequest, topology *VolumeTopology, candidates []string) (string, error)
+}
+
+// DeviceExtenderConfig is a single HTTP device-placement extender, modeled
+// on the Kubernetes scheduler extender protocol used by the host Scheduler.
+type DeviceExtenderConfig struct {
+	URL string
+	// Resources lists what this extender manages. Each entry is compared
+	// with the request's Role ("primary", "journal"); "device-names" matches
+	// every request, and an empty list matches every request too. An
+	// extender is only consulted for requests it matches. VolumeType is not
+	// looked at, and names such as "ebs-types" or "journal-placement" match
+	// only a Role of that exact name.
+	Resources []string
+	// Ignorable marks the extender as best-effort: if it errors or times
+	// out, PlaceDevice falls back to the local candidate list instead of
+	// failing the request.
+	Ignorable bool
+	Timeout   time.Duration
+}
+
+type deviceExtenderRequest struct {
+	Request    *DeviceRequest  `json:"Request"`
+	Topology   *VolumeTopology `json:"Topology"`
+	Candidates []string        `json:"Candidates"`
+}
+
+type deviceExtenderResponse struct {
+	Device string `json:"Device"`
+}
+
+// extenderDevicePlacer consults zero or more HTTP extenders before falling
+// back to the first local candidate. It is the default DevicePlacer once
+// any extender URL is configured.
+type extenderDevicePlacer struct {
+	extenders []DeviceExtenderConfig
+	client    *http.Client
+}
+
+// NewExtenderDevicePlacer creates a DevicePlacer that tries each configured
+// extender in order (skipping ones that don't declare the request's role),
+// falling back to the first local candidate if none respond or apply.
+func NewExtenderDevicePlacer(extenders []DeviceExtenderConfig) DevicePlacer {
+	return &extenderDevicePlacer{
+		extenders: extenders,
+		client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *extenderDevicePlacer) PlaceDevice(req *DeviceRequest, topology *VolumeTopology, candidates []string) (string, error) {
+	for _, ext := range p.extenders {
+		if !managesResource(ext.Resources, req.Role) {
+			continue
+		}
+
+		device, err := p.callExtender(ext, req, topology, candidates)
+		if err != nil {
+			if ext.Ignorable {
+				continue
+			}
+			return "", err
+		}
+		if device != "" {
+			return device, nil
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("scheduler: no candidate device for %s/%s role %s", req.ServiceName, req.MemberName, req.Role)
+	}
+	return candidates[0], nil
+}
+
+func (p *extenderDevicePlacer) callExtender(ext DeviceExtenderConfig, req *DeviceRequest, topology *VolumeTopology, candidates []string) (string, error) {
+	body, err := json.Marshal(&deviceExtenderRequest{Request: req, Topology: topology, Candidates: candidates})
+	if err != nil {
+		return "", err
+	}
+
+	client := p.client
+	if ext.Timeout > 0 {
+		client = &http.Client{Timeout: ext.Timeout}
+	}
+
+	resp, err := client.Post(ext.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("device extender error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var extResp deviceExtenderResponse
+	if err := json.NewDecoder(resp.Body).Decode(&extResp); err != nil {
+		return "", fmt.Errorf("device extender decode error: %v", err)
+	}
+	return extResp.Device, nil
+}
+
+func managesResource(resources []string, role string) bool {
+	if len(resources) == 0 {
+		// an extender declaring no resources manages everything.
+		return true
+	}
+	for _, r := range resources {
+		if r == role || r == "device-names" {
+			return true
+		}
+	}
+	return false
+}
+
+// localDevicePlacer is the existing behavior: always pick the first
+// candidate from the local allocator, e.g. createDevice's /dev/loopN
+// sequence.
+type localDevicePlacer struct{}
+
+// NewLocalDevicePlacer returns a DevicePlacer equivalent to today's
+// hard-coded createDevice behavior, used when no extenders are configured.
+func NewLocalDevicePlacer() DevicePlacer {
+	return &localDevicePlacer{}
+}
+
+func (localDevicePlacer) PlaceDevice(req *DeviceRequest, topology *VolumeTopology, candidates []string) (string, error) {
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("scheduler: no candidate device for %s/%s role %s", req.ServiceName, req.MemberName, req.Role)
+	}
+	return candidates[0], nil
+}
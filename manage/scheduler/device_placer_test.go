@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestTestDevicePlacerOverride asserts Override wins regardless of role and
+// that every call is recorded in Calls, so a test using this double can
+// assert on both the returned device and the requests it saw.
+func TestTestDevicePlacerOverride(t *testing.T) {
+	p := NewTestDevicePlacer()
+	p.Override = "/dev/xvdz"
+
+	req := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+	device, err := p.PlaceDevice(req, nil, []string{"/dev/xvdf"})
+	if err != nil {
+		t.Fatalf("PlaceDevice() error %s", err)
+	}
+	if device != "/dev/xvdz" {
+		t.Errorf("PlaceDevice() = %s, want /dev/xvdz", device)
+	}
+	if len(p.Calls) != 1 || p.Calls[0] != req {
+		t.Errorf("Calls = %v, want [%v]", p.Calls, req)
+	}
+}
+
+// TestTestDevicePlacerOverrideByRole asserts a role-specific override only
+// applies to that role, falling back to the local candidate for others.
+func TestTestDevicePlacerOverrideByRole(t *testing.T) {
+	p := NewTestDevicePlacer()
+	p.OverrideByRole["journal"] = "/dev/xvdj"
+
+	journalReq := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "journal"}
+	device, err := p.PlaceDevice(journalReq, nil, []string{"/dev/xvdf"})
+	if err != nil {
+		t.Fatalf("PlaceDevice(journal) error %s", err)
+	}
+	if device != "/dev/xvdj" {
+		t.Errorf("PlaceDevice(journal) = %s, want /dev/xvdj", device)
+	}
+
+	primaryReq := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+	device, err = p.PlaceDevice(primaryReq, nil, []string{"/dev/xvdf"})
+	if err != nil {
+		t.Fatalf("PlaceDevice(primary) error %s", err)
+	}
+	if device != "/dev/xvdf" {
+		t.Errorf("PlaceDevice(primary) = %s, want /dev/xvdf (local candidate)", device)
+	}
+}
+
+// TestTestDevicePlacerNoCandidates asserts PlaceDevice reports an error
+// rather than an empty device name when there is no override and no local
+// candidate to fall back to.
+func TestTestDevicePlacerNoCandidates(t *testing.T) {
+	p := NewTestDevicePlacer()
+	req := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+
+	if _, err := p.PlaceDevice(req, nil, nil); err == nil {
+		t.Fatal("PlaceDevice() error = nil, want error with no override and no candidates")
+	}
+}
+
+// TestLocalDevicePlacerUsesFirstCandidate asserts localDevicePlacer always
+// returns the first candidate, matching today's hard-coded createDevice
+// behavior.
+func TestLocalDevicePlacerUsesFirstCandidate(t *testing.T) {
+	p := NewLocalDevicePlacer()
+	req := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+
+	device, err := p.PlaceDevice(req, nil, []string{"/dev/xvdf", "/dev/xvdg"})
+	if err != nil {
+		t.Fatalf("PlaceDevice() error %s", err)
+	}
+	if device != "/dev/xvdf" {
+		t.Errorf("PlaceDevice() = %s, want /dev/xvdf", device)
+	}
+}
+
+// TestManagesResource asserts the resource-filter extenderDevicePlacer uses
+// to decide whether an extender is consulted for a given DeviceRequest.Role.
+func TestManagesResource(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources []string
+		role      string
+		want      bool
+	}{
+		{name: "empty resources manages everything", resources: nil, role: "primary", want: true},
+		{name: "exact role match", resources: []string{"journal"}, role: "journal", want: true},
+		{name: "device-names manages every role", resources: []string{"device-names"}, role: "primary", want: true},
+		{name: "no match", resources: []string{"journal"}, role: "primary", want: false},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := managesResource(tc.resources, tc.role); got != tc.want {
+				t.Errorf("managesResource(%v, %s) = %v, want %v", tc.resources, tc.role, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestExtenderDevicePlacerUsesExtenderResponse asserts PlaceDevice returns
+// the device an HTTP extender picks, over the local candidate list, when
+// the extender declares it manages the request's role.
+func TestExtenderDevicePlacerUsesExtenderResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&deviceExtenderResponse{Device: "/dev/xvdz"})
+	}))
+	defer srv.Close()
+
+	placer := NewExtenderDevicePlacer([]DeviceExtenderConfig{{URL: srv.URL, Resources: []string{"primary"}}})
+	req := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+
+	device, err := placer.PlaceDevice(req, &VolumeTopology{HostID: "host1"}, []string{"/dev/xvdf"})
+	if err != nil {
+		t.Fatalf("PlaceDevice() error %s", err)
+	}
+	if device != "/dev/xvdz" {
+		t.Errorf("PlaceDevice() = %s, want /dev/xvdz (extender response)", device)
+	}
+}
+
+// TestExtenderDevicePlacerSkipsExtenderForUnmanagedRole asserts an extender
+// that doesn't declare the request's role is never consulted, falling back
+// straight to the local candidate.
+func TestExtenderDevicePlacerSkipsExtenderForUnmanagedRole(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		json.NewEncoder(w).Encode(&deviceExtenderResponse{Device: "/dev/xvdz"})
+	}))
+	defer srv.Close()
+
+	placer := NewExtenderDevicePlacer([]DeviceExtenderConfig{{URL: srv.URL, Resources: []string{"journal"}}})
+	req := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+
+	device, err := placer.PlaceDevice(req, &VolumeTopology{HostID: "host1"}, []string{"/dev/xvdf"})
+	if err != nil {
+		t.Fatalf("PlaceDevice() error %s", err)
+	}
+	if device != "/dev/xvdf" {
+		t.Errorf("PlaceDevice() = %s, want /dev/xvdf (local fallback)", device)
+	}
+	if called {
+		t.Error("extender was called despite not managing role primary")
+	}
+}
+
+// TestExtenderDevicePlacerIgnorableFallsBackOnError asserts an Ignorable
+// extender that errors doesn't fail the request; PlaceDevice falls back to
+// the local candidate instead.
+func TestExtenderDevicePlacerIgnorableFallsBackOnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	placer := NewExtenderDevicePlacer([]DeviceExtenderConfig{{URL: srv.URL, Ignorable: true}})
+	req := &DeviceRequest{ServiceName: "svc", MemberName: "svc-0", Role: "primary"}
+
+	device, err := placer.PlaceDevice(req, &VolumeTopology{HostID: "host1"}, []string{"/dev/xvdf"})
+	if err != nil {
+		t.Fatalf("PlaceDevice() error %s", err)
+	}
+	if device != "/dev/xvdf" {
+		t.Errorf("PlaceDevice() = %s, want /dev/xvdf (fallback after ignorable extender error)", device)
+	}
+}
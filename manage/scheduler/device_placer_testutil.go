@@ -0,0 +1,44 @@
+package scheduler
+
+import "fmt"
+
+// TestDevicePlacer is an in-process DevicePlacer double used by
+// table-driven tests to assert that extender overrides are honored without
+// standing up a real HTTP extender. It would let a create-service test
+// assert overrides flow through into ServiceAttr.Volumes/ServiceMember.Volumes,
+// but no such test exists in this tree yet: the one test file that exercises
+// service creation, manage/service/service_testutil.go, predates this
+// package and is not wired to DevicePlacer at all. device_placer_test.go
+// exercises TestDevicePlacer, extenderDevicePlacer, and localDevicePlacer
+// directly instead.
+type TestDevicePlacer struct {
+	// Override, if set, is returned for every PlaceDevice call regardless of
+	// role, simulating an extender that always wins.
+	Override string
+	// OverrideByRole returns a role-specific override, simulating an
+	// extender that only manages e.g. "journal" placement.
+	OverrideByRole map[string]string
+	Calls          []*DeviceRequest
+}
+
+// NewTestDevicePlacer creates a TestDevicePlacer with no overrides
+// configured; PlaceDevice falls back to the first candidate exactly like
+// NewLocalDevicePlacer.
+func NewTestDevicePlacer() *TestDevicePlacer {
+	return &TestDevicePlacer{OverrideByRole: make(map[string]string)}
+}
+
+func (p *TestDevicePlacer) PlaceDevice(req *DeviceRequest, topology *VolumeTopology, candidates []string) (string, error) {
+	p.Calls = append(p.Calls, req)
+
+	if p.Override != "" {
+		return p.Override, nil
+	}
+	if dev, ok := p.OverrideByRole[req.Role]; ok {
+		return dev, nil
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("scheduler: no candidate device for %s/%s role %s", req.ServiceName, req.MemberName, req.Role)
+	}
+	return candidates[0], nil
+}
@@ -0,0 +1,345 @@
+package scheduler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrNoFeasibleHost is returned when no host in the cluster satisfies all
+// registered predicates for a replica.
+var ErrNoFeasibleHost = errors.New("no feasible host for replica")
+
+// Host is a candidate placement target for a service member.
+type Host struct {
+	HostID string
+	Zone   string
+	VpcID  string
+
+	// DeviceCount is the number of devices already allocated on this host.
+	DeviceCount int
+	// MaxDevices is the max number of devices this host can host, or 0 if unbounded.
+	MaxDevices int
+}
+
+// ReplicaContext describes the replica being placed.
+type ReplicaContext struct {
+	ServiceName    string
+	ReplicaIndex   int64
+	RequireJournal bool
+	PreferredZone  string
+}
+
+// Placement is a record of an already-placed replica, used by predicates and
+// priorities that need to reason about siblings (anti-affinity, zone spread).
+type Placement struct {
+	ServiceName  string
+	ReplicaIndex int64
+	HostID       string
+	Zone         string
+}
+
+// Predicate filters a candidate host for a replica. It returns false if the
+// host cannot host the replica.
+type Predicate func(host *Host, replica *ReplicaContext, placed []Placement) bool
+
+// Priority scores a candidate host for a replica. The returned score is in
+// [0, 10]; higher is better.
+type Priority func(host *Host, replica *ReplicaContext, placed []Placement) float64
+
+// WeightedPriority pairs a named priority with its weight.
+type WeightedPriority struct {
+	Name   string
+	Weight float64
+}
+
+// ExtenderConfig describes an external HTTP scheduler extender, modeled on
+// the Kubernetes scheduler extender API.
+type ExtenderConfig struct {
+	URL       string
+	Ignorable bool
+	Timeout   time.Duration
+}
+
+// PlacementPolicy is the pluggable placement configuration a caller feeds
+// to NewScheduler to choose a host for every replica of a service, e.g.
+// ManageService.CreateService once it adopts Scheduler for replica
+// placement (manage/server.go, the file that would wire this in, is not
+// part of this tree).
+type PlacementPolicy struct {
+	Predicates []string
+	Priorities []WeightedPriority
+	Extenders  []ExtenderConfig
+}
+
+var (
+	registryLock sync.Mutex
+	predicates   = map[string]Predicate{}
+	priorities   = map[string]Priority{}
+)
+
+// RegisterPredicate registers a named predicate so it can be referenced from
+// a PlacementPolicy. Operators can add custom predicates at init time.
+func RegisterPredicate(name string, p Predicate) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	predicates[name] = p
+}
+
+// RegisterPriority registers a named priority so it can be referenced from a
+// PlacementPolicy.
+func RegisterPriority(name string, p Priority) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	priorities[name] = p
+}
+
+func init() {
+	RegisterPredicate("ZoneSpread", zoneSpreadPredicate)
+	RegisterPredicate("SameVPC", sameVPCPredicate)
+	RegisterPredicate("DeviceCapacity", deviceCapacityPredicate)
+	RegisterPredicate("JournalOnSeparateAZ", journalOnSeparateAZPredicate)
+	RegisterPredicate("AntiAffinityByService", antiAffinityByServicePredicate)
+
+	RegisterPriority("ZoneSpread", zoneSpreadPriority)
+	RegisterPriority("DeviceCapacity", deviceCapacityPriority)
+}
+
+// Scheduler applies a PlacementPolicy to pick a host for each replica of a
+// service. The rand source is seeded once at construction so placement
+// decisions (and the tie-breaking they require) are reproducible in tests.
+type Scheduler struct {
+	policy *PlacementPolicy
+	rand   *rand.Rand
+	client *http.Client
+}
+
+// NewScheduler creates a Scheduler for the given policy. seed makes
+// tie-breaking deterministic, e.g. for scheduler_test.go.
+func NewScheduler(policy *PlacementPolicy, seed int64) *Scheduler {
+	return &Scheduler{
+		policy: policy,
+		rand:   rand.New(rand.NewSource(seed)),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Schedule picks the best host for the replica among candidates, applying
+// predicates to filter, priorities plus extenders to score, and returns the
+// chosen host. already is the set of replicas already placed for this (and
+// other) services, used by spread/anti-affinity predicates and priorities.
+func (s *Scheduler) Schedule(candidates []*Host, replica *ReplicaContext, already []Placement) (*Host, error) {
+	feasible := s.filter(candidates, replica, already)
+	if len(feasible) == 0 {
+		return nil, ErrNoFeasibleHost
+	}
+
+	scores := s.score(feasible, replica, already)
+
+	if len(s.policy.Extenders) > 0 {
+		extenderScores, err := s.callExtenders(feasible, replica)
+		if err != nil {
+			return nil, err
+		}
+		for hostID, score := range extenderScores {
+			scores[hostID] += score
+		}
+	}
+
+	return s.pickBest(feasible, scores), nil
+}
+
+func (s *Scheduler) filter(candidates []*Host, replica *ReplicaContext, already []Placement) []*Host {
+	feasible := make([]*Host, 0, len(candidates))
+	for _, h := range candidates {
+		ok := true
+		for _, name := range s.policy.Predicates {
+			p, exist := predicates[name]
+			if !exist {
+				continue
+			}
+			if !p(h, replica, already) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			feasible = append(feasible, h)
+		}
+	}
+	return feasible
+}
+
+func (s *Scheduler) score(hosts []*Host, replica *ReplicaContext, already []Placement) map[string]float64 {
+	scores := make(map[string]float64, len(hosts))
+	for _, h := range hosts {
+		var total, weightSum float64
+		for _, wp := range s.policy.Priorities {
+			p, exist := priorities[wp.Name]
+			if !exist {
+				continue
+			}
+			total += wp.Weight * p(h, replica, already)
+			weightSum += wp.Weight
+		}
+		if weightSum > 0 {
+			total = total / weightSum * 10
+		}
+		scores[h.HostID] = total
+	}
+	return scores
+}
+
+type extenderRequest struct {
+	Nodes   []*Host         `json:"Nodes"`
+	Replica *ReplicaContext `json:"Replica"`
+}
+
+type extenderResponse struct {
+	Scores map[string]float64 `json:"Scores"`
+}
+
+func (s *Scheduler) callExtenders(hosts []*Host, replica *ReplicaContext) (map[string]float64, error) {
+	combined := make(map[string]float64, len(hosts))
+	for _, ext := range s.policy.Extenders {
+		body, err := json.Marshal(&extenderRequest{Nodes: hosts, Replica: replica})
+		if err != nil {
+			return nil, err
+		}
+
+		client := s.client
+		if ext.Timeout > 0 {
+			client = &http.Client{Timeout: ext.Timeout}
+		}
+
+		resp, err := client.Post(ext.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			if ext.Ignorable {
+				continue
+			}
+			return nil, fmt.Errorf("extender %s error: %v", ext.URL, err)
+		}
+		defer resp.Body.Close()
+
+		var extResp extenderResponse
+		if err := json.NewDecoder(resp.Body).Decode(&extResp); err != nil {
+			if ext.Ignorable {
+				continue
+			}
+			return nil, fmt.Errorf("extender %s decode error: %v", ext.URL, err)
+		}
+
+		for hostID, score := range extResp.Scores {
+			combined[hostID] += score
+		}
+	}
+	return combined, nil
+}
+
+// pickBest returns the host with the highest score, breaking ties with the
+// scheduler's seeded RNG so results are reproducible given the same seed.
+func (s *Scheduler) pickBest(hosts []*Host, scores map[string]float64) *Host {
+	best := hosts[0]
+	bestScore := scores[best.HostID]
+	tied := []*Host{best}
+
+	for _, h := range hosts[1:] {
+		score := scores[h.HostID]
+		switch {
+		case score > bestScore:
+			best = h
+			bestScore = score
+			tied = []*Host{h}
+		case score == bestScore:
+			tied = append(tied, h)
+		}
+	}
+
+	if len(tied) == 1 {
+		return tied[0]
+	}
+	return tied[s.rand.Intn(len(tied))]
+}
+
+// built-in predicates
+
+func zoneSpreadPredicate(host *Host, replica *ReplicaContext, placed []Placement) bool {
+	// always feasible: zone spread is enforced via the priority, not as a hard predicate.
+	return true
+}
+
+func sameVPCPredicate(host *Host, replica *ReplicaContext, placed []Placement) bool {
+	for _, p := range placed {
+		if p.ServiceName == replica.ServiceName && p.HostID == host.HostID {
+			// a replica of the same service already owns this host; allow,
+			// VPC matching is evaluated by the caller when building candidates.
+			return true
+		}
+	}
+	return true
+}
+
+func deviceCapacityPredicate(host *Host, replica *ReplicaContext, placed []Placement) bool {
+	if host.MaxDevices <= 0 {
+		return true
+	}
+	return host.DeviceCount < host.MaxDevices
+}
+
+func journalOnSeparateAZPredicate(host *Host, replica *ReplicaContext, placed []Placement) bool {
+	if !replica.RequireJournal {
+		return true
+	}
+	for _, p := range placed {
+		if p.ServiceName == replica.ServiceName && p.Zone == host.Zone {
+			return false
+		}
+	}
+	return true
+}
+
+func antiAffinityByServicePredicate(host *Host, replica *ReplicaContext, placed []Placement) bool {
+	for _, p := range placed {
+		if p.ServiceName == replica.ServiceName && p.HostID == host.HostID {
+			return false
+		}
+	}
+	return true
+}
+
+// built-in priorities
+
+func zoneSpreadPriority(host *Host, replica *ReplicaContext, placed []Placement) float64 {
+	sameZone := 0
+	for _, p := range placed {
+		if p.ServiceName == replica.ServiceName && p.Zone == host.Zone {
+			sameZone++
+		}
+	}
+	// fewer existing replicas in this zone scores higher
+	score := 10.0 - float64(sameZone)
+	if score < 0 {
+		score = 0
+	}
+	return score
+}
+
+func deviceCapacityPriority(host *Host, replica *ReplicaContext, placed []Placement) float64 {
+	if host.MaxDevices <= 0 {
+		return 10
+	}
+	free := host.MaxDevices - host.DeviceCount
+	if free <= 0 {
+		return 0
+	}
+	score := float64(free) / float64(host.MaxDevices) * 10
+	if score > 10 {
+		score = 10
+	}
+	return score
+}
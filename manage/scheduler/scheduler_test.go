@@ -0,0 +1,107 @@
+package scheduler
+
+import "testing"
+
+func hostsByZone(zones ...string) []*Host {
+	hosts := make([]*Host, len(zones))
+	for i, z := range zones {
+		hosts[i] = &Host{HostID: z + "-host", Zone: z}
+	}
+	return hosts
+}
+
+// TestScheduleSpreadsAcrossZones asserts that, with the ZoneSpread
+// priority enabled, replicas of the same service land on hosts in zones
+// that don't already hold a replica of that service, so a spread policy
+// actually spreads instead of piling onto the first feasible host.
+func TestScheduleSpreadsAcrossZones(t *testing.T) {
+	policy := &PlacementPolicy{
+		Priorities: []WeightedPriority{{Name: "ZoneSpread", Weight: 1}},
+	}
+	s := NewScheduler(policy, 1)
+
+	hosts := hostsByZone("az1", "az2", "az3")
+	replica := &ReplicaContext{ServiceName: "svc", ReplicaIndex: 0}
+
+	var placed []Placement
+	for i := 0; i < 3; i++ {
+		replica.ReplicaIndex = int64(i)
+		host, err := s.Schedule(hosts, replica, placed)
+		if err != nil {
+			t.Fatalf("Schedule() replica %d error %v", i, err)
+		}
+		for _, p := range placed {
+			if p.Zone == host.Zone {
+				t.Fatalf("replica %d landed in zone %s, already used by %v", i, host.Zone, placed)
+			}
+		}
+		placed = append(placed, Placement{ServiceName: "svc", ReplicaIndex: int64(i), HostID: host.HostID, Zone: host.Zone})
+	}
+}
+
+// TestScheduleAntiAffinityExcludesOccupiedHost asserts that
+// AntiAffinityByService makes a host ineligible once it already hosts a
+// replica of the same service, even when it's the only host offered.
+func TestScheduleAntiAffinityExcludesOccupiedHost(t *testing.T) {
+	policy := &PlacementPolicy{Predicates: []string{"AntiAffinityByService"}}
+	s := NewScheduler(policy, 1)
+
+	hosts := []*Host{{HostID: "h1", Zone: "az1"}}
+	replica := &ReplicaContext{ServiceName: "svc", ReplicaIndex: 1}
+	placed := []Placement{{ServiceName: "svc", ReplicaIndex: 0, HostID: "h1", Zone: "az1"}}
+
+	if _, err := s.Schedule(hosts, replica, placed); err != ErrNoFeasibleHost {
+		t.Fatalf("Schedule() error = %v, want ErrNoFeasibleHost", err)
+	}
+}
+
+// TestScheduleJournalOnSeparateAZExcludesSameZone asserts that a replica
+// requiring its journal on a separate AZ can't be placed in a zone that
+// already hosts a sibling replica.
+func TestScheduleJournalOnSeparateAZExcludesSameZone(t *testing.T) {
+	policy := &PlacementPolicy{Predicates: []string{"JournalOnSeparateAZ"}}
+	s := NewScheduler(policy, 1)
+
+	hosts := hostsByZone("az1")
+	replica := &ReplicaContext{ServiceName: "svc", ReplicaIndex: 1, RequireJournal: true}
+	placed := []Placement{{ServiceName: "svc", ReplicaIndex: 0, HostID: "az1-host", Zone: "az1"}}
+
+	if _, err := s.Schedule(hosts, replica, placed); err != ErrNoFeasibleHost {
+		t.Fatalf("Schedule() error = %v, want ErrNoFeasibleHost", err)
+	}
+}
+
+// TestScheduleDeviceCapacityPredicateExcludesFullHost asserts that a host
+// at its MaxDevices limit is filtered out rather than overloaded further.
+func TestScheduleDeviceCapacityPredicateExcludesFullHost(t *testing.T) {
+	policy := &PlacementPolicy{Predicates: []string{"DeviceCapacity"}}
+	s := NewScheduler(policy, 1)
+
+	full := &Host{HostID: "full", MaxDevices: 1, DeviceCount: 1}
+	free := &Host{HostID: "free", MaxDevices: 1, DeviceCount: 0}
+	replica := &ReplicaContext{ServiceName: "svc"}
+
+	host, err := s.Schedule([]*Host{full, free}, replica, nil)
+	if err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	if host.HostID != "free" {
+		t.Fatalf("Schedule() = %s, want free", host.HostID)
+	}
+}
+
+// TestScheduleNoFeasibleHost asserts Schedule reports ErrNoFeasibleHost
+// rather than panicking or picking an unsuitable host when every
+// candidate is filtered out.
+func TestScheduleNoFeasibleHost(t *testing.T) {
+	policy := &PlacementPolicy{Predicates: []string{"AntiAffinityByService"}}
+	s := NewScheduler(policy, 1)
+
+	hosts := []*Host{{HostID: "h1"}}
+	replica := &ReplicaContext{ServiceName: "svc"}
+	placed := []Placement{{ServiceName: "svc", HostID: "h1"}}
+
+	if _, err := s.Schedule(hosts, replica, placed); err != ErrNoFeasibleHost {
+		t.Fatalf("Schedule() error = %v, want ErrNoFeasibleHost", err)
+	}
+}
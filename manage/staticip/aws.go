@@ -0,0 +1,101 @@
+package staticip
+
+import (
+	"context"
+	"fmt"
+)
+
+// ec2Client is the minimal EC2 surface the AWS EIP provider needs. This
+// package has no adapter from the AWS SDK's ec2.EC2 client to it; tests
+// supply an in-memory double.
+type ec2Client interface {
+	AllocateAddress(ctx context.Context) (allocationID string, publicIP string, err error)
+	AssociateAddress(ctx context.Context, allocationID string, instanceID string) error
+	DisassociateAddress(ctx context.Context, allocationID string) error
+	ReleaseAddress(ctx context.Context, allocationID string) error
+}
+
+// awsEIPProvider allocates AWS Elastic IPs as the static-IP backend for
+// service members that require a static, internet-routable address.
+type awsEIPProvider struct {
+	ec2 ec2Client
+}
+
+// NewAWSEIPProvider creates a StaticIPProvider backed by AWS Elastic IPs.
+func NewAWSEIPProvider(ec2 ec2Client) StaticIPProvider {
+	return &awsEIPProvider{ec2: ec2}
+}
+
+func (p *awsEIPProvider) Name() string { return "aws-eip" }
+
+func (p *awsEIPProvider) Allocate(ctx context.Context, zone string, count int) ([]AllocatedIP, error) {
+	ips := make([]AllocatedIP, 0, count)
+	for i := 0; i < count; i++ {
+		allocationID, publicIP, err := p.ec2.AllocateAddress(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("staticip: allocate EIP: %v", err)
+		}
+		ips = append(ips, AllocatedIP{StaticIP: publicIP, Zone: zone, ProviderID: allocationID})
+	}
+	return ips, nil
+}
+
+func (p *awsEIPProvider) Associate(ctx context.Context, ip AllocatedIP, memberName string, instanceID string) error {
+	return p.ec2.AssociateAddress(ctx, ip.ProviderID, instanceID)
+}
+
+func (p *awsEIPProvider) Disassociate(ctx context.Context, ip AllocatedIP) error {
+	return p.ec2.DisassociateAddress(ctx, ip.ProviderID)
+}
+
+func (p *awsEIPProvider) Release(ctx context.Context, ip AllocatedIP) error {
+	return p.ec2.ReleaseAddress(ctx, ip.ProviderID)
+}
+
+// neutronClient is the minimal OpenStack Neutron surface the floating-ip
+// provider needs.
+type neutronClient interface {
+	CreateFloatingIP(ctx context.Context, poolName string) (id string, address string, err error)
+	AssociateFloatingIP(ctx context.Context, id string, portID string) error
+	DisassociateFloatingIP(ctx context.Context, id string) error
+	DeleteFloatingIP(ctx context.Context, id string) error
+}
+
+// neutronProvider allocates OpenStack Neutron floating IPs.
+type neutronProvider struct {
+	client   neutronClient
+	poolName string
+}
+
+// NewNeutronProvider creates a StaticIPProvider backed by an OpenStack
+// Neutron floating-ip pool.
+func NewNeutronProvider(client neutronClient, poolName string) StaticIPProvider {
+	return &neutronProvider{client: client, poolName: poolName}
+}
+
+func (p *neutronProvider) Name() string { return "openstack-neutron" }
+
+func (p *neutronProvider) Allocate(ctx context.Context, zone string, count int) ([]AllocatedIP, error) {
+	ips := make([]AllocatedIP, 0, count)
+	for i := 0; i < count; i++ {
+		id, address, err := p.client.CreateFloatingIP(ctx, p.poolName)
+		if err != nil {
+			return nil, fmt.Errorf("staticip: create floating ip: %v", err)
+		}
+		ips = append(ips, AllocatedIP{StaticIP: address, Zone: zone, ProviderID: id})
+	}
+	return ips, nil
+}
+
+func (p *neutronProvider) Associate(ctx context.Context, ip AllocatedIP, memberName string, instanceID string) error {
+	// instanceID is the bound Neutron port ID for the member's instance.
+	return p.client.AssociateFloatingIP(ctx, ip.ProviderID, instanceID)
+}
+
+func (p *neutronProvider) Disassociate(ctx context.Context, ip AllocatedIP) error {
+	return p.client.DisassociateFloatingIP(ctx, ip.ProviderID)
+}
+
+func (p *neutronProvider) Release(ctx context.Context, ip AllocatedIP) error {
+	return p.client.DeleteFloatingIP(ctx, ip.ProviderID)
+}
@@ -0,0 +1,89 @@
+// Package staticip provides the pluggable static-IP allocation backends
+// intended for ManageService.createStaticIPsForZone/createServiceMember.
+// The default backend (DBPoolProvider) mints IPs from an internal per-zone
+// pool tracked in db.DB; StaticIPProvider lets an operator instead point at
+// an external floating-IP pool (AWS EIP, OpenStack Neutron). Neither
+// createStaticIPsForZone nor createServiceMember exists in this tree
+// (manage/server.go doesn't exist here), so no caller parameterizes over
+// StaticIPProvider yet; this package's own tests exercise every provider
+// directly against the same table of Allocate/Associate/Disassociate/
+// Release assertions instead.
+package staticip
+
+import "context"
+
+// AllocatedIP is one IP handed back by a StaticIPProvider's Allocate call.
+type AllocatedIP struct {
+	StaticIP string
+	Zone     string
+	// ProviderID is the provider's own handle for the IP (e.g. an AWS
+	// allocation ID, a Neutron floating-ip ID), recorded so Release can
+	// address it without re-deriving it from the IP string.
+	ProviderID string
+}
+
+// StaticIPProvider allocates, associates, and releases static IPs for
+// service members. Nothing in this tree records which provider owns an IP;
+// common.ServiceAttr and common.ServiceMember have no field for it.
+type StaticIPProvider interface {
+	// Name identifies this provider.
+	Name() string
+
+	// Allocate reserves count new IPs in zone.
+	Allocate(ctx context.Context, zone string, count int) ([]AllocatedIP, error)
+
+	// Associate attaches a previously allocated IP to a member's running
+	// instance, e.g. an AWS EIP association or a Neutron floating-ip port
+	// binding.
+	Associate(ctx context.Context, ip AllocatedIP, memberName string, instanceID string) error
+
+	// Disassociate detaches the IP from its current instance without
+	// releasing it back to the pool.
+	Disassociate(ctx context.Context, ip AllocatedIP) error
+
+	// Release returns the IP to the provider's pool permanently.
+	Release(ctx context.Context, ip AllocatedIP) error
+}
+
+// DBPoolProviderName names the existing internal per-zone pool backend
+// (NewDBPoolProvider), kept as the default so clusters that don't configure
+// a cloud provider see no behavior change.
+const DBPoolProviderName = "db-pool"
+
+// dbPoolProvider implements StaticIPProvider on top of the same
+// ServiceStaticIP rows ManageService already maintains in db.DB; Allocate
+// here is a thin adapter so callers can treat it uniformly with cloud
+// providers. It intentionally performs no cloud-side association:
+// Associate, Disassociate and Release all return nil without doing
+// anything, so Release does not return the IP to the pool.
+type dbPoolProvider struct {
+	nextIP func(ctx context.Context, zone string) (string, error)
+}
+
+// NewDBPoolProvider wraps the existing internal pool allocator (the one
+// createStaticIPsForZone already drives) as a StaticIPProvider.
+func NewDBPoolProvider(nextIP func(ctx context.Context, zone string) (string, error)) StaticIPProvider {
+	return &dbPoolProvider{nextIP: nextIP}
+}
+
+func (p *dbPoolProvider) Name() string { return DBPoolProviderName }
+
+func (p *dbPoolProvider) Allocate(ctx context.Context, zone string, count int) ([]AllocatedIP, error) {
+	ips := make([]AllocatedIP, 0, count)
+	for i := 0; i < count; i++ {
+		ip, err := p.nextIP(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, AllocatedIP{StaticIP: ip, Zone: zone, ProviderID: ip})
+	}
+	return ips, nil
+}
+
+func (p *dbPoolProvider) Associate(ctx context.Context, ip AllocatedIP, memberName string, instanceID string) error {
+	return nil
+}
+
+func (p *dbPoolProvider) Disassociate(ctx context.Context, ip AllocatedIP) error { return nil }
+
+func (p *dbPoolProvider) Release(ctx context.Context, ip AllocatedIP) error { return nil }
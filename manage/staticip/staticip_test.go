@@ -0,0 +1,171 @@
+package staticip
+
+import (
+	"context"
+	"testing"
+)
+
+// memEC2Client is an in-memory ec2Client double.
+type memEC2Client struct {
+	next      int
+	allocated map[string]string // allocationID -> associated instanceID ("" if none)
+}
+
+func newMemEC2Client() *memEC2Client {
+	return &memEC2Client{allocated: make(map[string]string)}
+}
+
+func (c *memEC2Client) AllocateAddress(ctx context.Context) (string, string, error) {
+	c.next++
+	id := "eipalloc-" + itoa(c.next)
+	c.allocated[id] = ""
+	return id, "203.0.113." + itoa(c.next), nil
+}
+
+func (c *memEC2Client) AssociateAddress(ctx context.Context, allocationID string, instanceID string) error {
+	c.allocated[allocationID] = instanceID
+	return nil
+}
+
+func (c *memEC2Client) DisassociateAddress(ctx context.Context, allocationID string) error {
+	c.allocated[allocationID] = ""
+	return nil
+}
+
+func (c *memEC2Client) ReleaseAddress(ctx context.Context, allocationID string) error {
+	delete(c.allocated, allocationID)
+	return nil
+}
+
+// memNeutronClient is an in-memory neutronClient double.
+type memNeutronClient struct {
+	next      int
+	allocated map[string]string // floating-ip id -> associated port ID ("" if none)
+}
+
+func newMemNeutronClient() *memNeutronClient {
+	return &memNeutronClient{allocated: make(map[string]string)}
+}
+
+func (c *memNeutronClient) CreateFloatingIP(ctx context.Context, poolName string) (string, string, error) {
+	c.next++
+	id := "fip-" + itoa(c.next)
+	c.allocated[id] = ""
+	return id, "198.51.100." + itoa(c.next), nil
+}
+
+func (c *memNeutronClient) AssociateFloatingIP(ctx context.Context, id string, portID string) error {
+	c.allocated[id] = portID
+	return nil
+}
+
+func (c *memNeutronClient) DisassociateFloatingIP(ctx context.Context, id string) error {
+	c.allocated[id] = ""
+	return nil
+}
+
+func (c *memNeutronClient) DeleteFloatingIP(ctx context.Context, id string) error {
+	delete(c.allocated, id)
+	return nil
+}
+
+// itoa avoids importing strconv for a single-digit-friendly test helper.
+func itoa(n int) string {
+	if n == 0 {
+		return "0"
+	}
+	digits := []byte{}
+	for n > 0 {
+		digits = append([]byte{byte('0' + n%10)}, digits...)
+		n /= 10
+	}
+	return string(digits)
+}
+
+// TestStaticIPProviders parameterizes the same Allocate/Associate/
+// Disassociate/Release matrix across every StaticIPProvider implementation,
+// so adding a new provider without satisfying the same contract as the
+// existing ones fails the test instead of surfacing as a runtime bug.
+func TestStaticIPProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider StaticIPProvider
+		wantName string
+	}{
+		{
+			name: "db-pool",
+			provider: NewDBPoolProvider(func(ctx context.Context, zone string) (string, error) {
+				return "10.0.0.4", nil
+			}),
+			wantName: DBPoolProviderName,
+		},
+		{
+			name:     "aws-eip",
+			provider: NewAWSEIPProvider(newMemEC2Client()),
+			wantName: "aws-eip",
+		},
+		{
+			name:     "openstack-neutron",
+			provider: NewNeutronProvider(newMemNeutronClient(), "public"),
+			wantName: "openstack-neutron",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.provider.Name(); got != tc.wantName {
+				t.Fatalf("Name() = %q, want %q", got, tc.wantName)
+			}
+
+			ctx := context.Background()
+			ips, err := tc.provider.Allocate(ctx, "az1", 2)
+			if err != nil {
+				t.Fatalf("Allocate() error %s", err)
+			}
+			if len(ips) != 2 {
+				t.Fatalf("Allocate() returned %d IPs, want 2", len(ips))
+			}
+			for _, ip := range ips {
+				if ip.StaticIP == "" || ip.ProviderID == "" {
+					t.Fatalf("Allocate() returned incomplete AllocatedIP %+v", ip)
+				}
+				if ip.Zone != "az1" {
+					t.Errorf("AllocatedIP.Zone = %q, want az1", ip.Zone)
+				}
+			}
+
+			if err := tc.provider.Associate(ctx, ips[0], "member-0", "i-0123456789"); err != nil {
+				t.Fatalf("Associate() error %s", err)
+			}
+			if err := tc.provider.Disassociate(ctx, ips[0]); err != nil {
+				t.Fatalf("Disassociate() error %s", err)
+			}
+			if err := tc.provider.Release(ctx, ips[0]); err != nil {
+				t.Fatalf("Release() error %s", err)
+			}
+			if err := tc.provider.Release(ctx, ips[1]); err != nil {
+				t.Fatalf("Release() error %s", err)
+			}
+		})
+	}
+}
+
+// TestAWSEIPProviderAssociateUsesAllocationID asserts Associate/Disassociate
+// address the EC2 client by the EIP's allocation ID, not its public IP.
+func TestAWSEIPProviderAssociateUsesAllocationID(t *testing.T) {
+	ec2 := newMemEC2Client()
+	p := NewAWSEIPProvider(ec2)
+
+	ips, err := p.Allocate(context.Background(), "az1", 1)
+	if err != nil {
+		t.Fatalf("Allocate() error %s", err)
+	}
+
+	if err := p.Associate(context.Background(), ips[0], "member-0", "i-0123456789"); err != nil {
+		t.Fatalf("Associate() error %s", err)
+	}
+	if ec2.allocated[ips[0].ProviderID] != "i-0123456789" {
+		t.Fatalf("allocated[%s] = %q, want i-0123456789", ips[0].ProviderID, ec2.allocated[ips[0].ProviderID])
+	}
+}
@@ -0,0 +1,116 @@
+// Package taskdef translates a service's volume configuration into the
+// fields the manage server hands to ECS's RegisterTaskDefinition/RunTask,
+// covering both the default cloudstax/firecamp-volume docker plugin and the
+// newer ECS-native managed EBS volume.
+package taskdef
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cloudstax/firecamp/common"
+)
+
+// EBSVolumeConfig is the parsed form of the ENV_EBS_* environment keys, shaped
+// directly into what an ecs.ServiceManagedEBSVolumeConfiguration needs.
+type EBSVolumeConfig struct {
+	SizeGB         int64
+	Iops           int64
+	Throughput     int64
+	FileSystemType string
+	KmsKeyID       string
+	SnapshotID     string
+	RoleArn        string
+}
+
+// UsesECSManagedVolume reports whether volumeType opts a service into
+// ECS-native managed EBS volumes instead of the firecamp docker volume
+// plugin.
+func UsesECSManagedVolume(volumeType string) bool {
+	return volumeType == common.VolumeTypeECSManagedEBS
+}
+
+// ParseEBSVolumeConfig reads the ENV_EBS_* keys out of env (and the
+// volume's size, the one field that isn't environment-driven) into an
+// EBSVolumeConfig. RoleArn is required: ECS assumes it to attach the
+// volume to the task, so a missing value is a configuration error, not a
+// field to silently default.
+func ParseEBSVolumeConfig(env []*common.EnvKeyValuePair, volumeSizeGB int64) (*EBSVolumeConfig, error) {
+	cfg := &EBSVolumeConfig{SizeGB: volumeSizeGB}
+
+	for _, kv := range env {
+		switch kv.Name {
+		case common.ENV_EBS_VOLUME_IOPS:
+			iops, err := strconv.ParseInt(kv.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("taskdef: invalid %s %q: %v", common.ENV_EBS_VOLUME_IOPS, kv.Value, err)
+			}
+			cfg.Iops = iops
+		case common.ENV_EBS_VOLUME_THROUGHPUT:
+			throughput, err := strconv.ParseInt(kv.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("taskdef: invalid %s %q: %v", common.ENV_EBS_VOLUME_THROUGHPUT, kv.Value, err)
+			}
+			cfg.Throughput = throughput
+		case common.ENV_EBS_FILESYSTEM_TYPE:
+			cfg.FileSystemType = kv.Value
+		case common.ENV_EBS_KMS_KEY_ID:
+			cfg.KmsKeyID = kv.Value
+		case common.ENV_EBS_SNAPSHOT_ID:
+			cfg.SnapshotID = kv.Value
+		case common.ENV_EBS_ROLE_ARN:
+			cfg.RoleArn = kv.Value
+		}
+	}
+
+	if cfg.RoleArn == "" {
+		return nil, fmt.Errorf("taskdef: %s is required for %s volumes", common.ENV_EBS_ROLE_ARN, common.VolumeTypeECSManagedEBS)
+	}
+	if cfg.FileSystemType == "" {
+		cfg.FileSystemType = "xfs"
+	}
+
+	return cfg, nil
+}
+
+// ECSManagedEBSVolume is the subset of ecs.ServiceManagedEBSVolumeConfiguration
+// RegisterTaskDefinition/RunTask needs, kept as a local type so this package
+// doesn't force an aws-sdk-go dependency on every caller; the manageserver's
+// ECS adapter copies these fields into the real SDK struct at the call site.
+type ECSManagedEBSVolume struct {
+	Name           string
+	SizeInGiB      int64
+	VolumeType     string
+	Iops           int64
+	Throughput     int64
+	FileSystemType string
+	KmsKeyID       string
+	SnapshotID     string
+	RoleArn        string
+	Encrypted      bool
+}
+
+// BuildECSManagedEBSVolume builds the managedEBSVolume task definition
+// field for a service's volume named volumeName, choosing gp3 when IOPS or
+// throughput were requested (both are gp3-only) and falling back to io2
+// otherwise, since io2 is the closest durability match to the EBS volumes
+// firecamp already provisions for the docker plugin path.
+func BuildECSManagedEBSVolume(volumeName string, cfg *EBSVolumeConfig) *ECSManagedEBSVolume {
+	volumeType := "io2"
+	if cfg.Iops > 0 || cfg.Throughput > 0 {
+		volumeType = "gp3"
+	}
+
+	return &ECSManagedEBSVolume{
+		Name:           volumeName,
+		SizeInGiB:      cfg.SizeGB,
+		VolumeType:     volumeType,
+		Iops:           cfg.Iops,
+		Throughput:     cfg.Throughput,
+		FileSystemType: cfg.FileSystemType,
+		KmsKeyID:       cfg.KmsKeyID,
+		SnapshotID:     cfg.SnapshotID,
+		RoleArn:        cfg.RoleArn,
+		Encrypted:      cfg.KmsKeyID != "",
+	}
+}
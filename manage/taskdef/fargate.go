@@ -0,0 +1,166 @@
+package taskdef
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cloudstax/firecamp/common"
+)
+
+// fargateCPUMemoryOptions are AWS Fargate's valid (task CPU units, task
+// memory MB) combinations. Fargate bills and schedules at the task level,
+// not per container, and only accepts this fixed set of pairs.
+var fargateCPUMemoryOptions = []struct {
+	CPU        int64
+	MinMemory  int64
+	MaxMemory  int64
+	MemoryStep int64
+}{
+	{256, 512, 2048, 1024},
+	{512, 1024, 4096, 1024},
+	{1024, 2048, 8192, 1024},
+	{2048, 4096, 16384, 1024},
+	{4096, 8192, 30720, 1024},
+}
+
+// RequiresAWSVPC reports whether platform only runs tasks with awsvpc
+// networking, i.e. the task ENI Fargate requires rather than the bridge
+// networking the docker volume plugin path uses.
+func RequiresAWSVPC(platform string) bool {
+	return platform == common.ContainerPlatformFargate || platform == common.ContainerPlatformECSFargate
+}
+
+// SkipsDockerVolumePlugin reports whether platform never runs on EC2
+// container instances, so the cloudstax/firecamp-volume docker volume
+// plugin path must be skipped in favor of an ECS-managed EBS volume.
+func SkipsDockerVolumePlugin(platform string) bool {
+	return platform == common.ContainerPlatformFargate
+}
+
+// FargateCPUMemory translates a service's EC2-style reserved CPU units and
+// memory MB (DefaultReserveCPUUnits/DefaultReserveMemoryMB) into the
+// smallest valid Fargate task-level (cpu, memory) tuple that can fit both,
+// rounding up since Fargate doesn't allow requesting less than a task
+// asks for.
+func FargateCPUMemory(reserveCPUUnits, reserveMemoryMB int64) (cpu int64, memoryMB int64) {
+	for _, opt := range fargateCPUMemoryOptions {
+		if opt.CPU < reserveCPUUnits || opt.MaxMemory < reserveMemoryMB {
+			continue
+		}
+		mem := opt.MinMemory
+		for mem < reserveMemoryMB {
+			mem += opt.MemoryStep
+		}
+		return opt.CPU, mem
+	}
+	// reserveMemoryMB exceeds every option's max; return the largest
+	// Fargate size and let ECS reject the task definition if it's still
+	// too small, rather than silently capping the service's memory.
+	last := fargateCPUMemoryOptions[len(fargateCPUMemoryOptions)-1]
+	return last.CPU, last.MaxMemory
+}
+
+// CapacityProvider is one weighted entry of an ECS capacity-provider
+// strategy, as parsed from a single ENV_CAPACITY_PROVIDERS "name:weight:base"
+// item.
+type CapacityProvider struct {
+	Name   string
+	Weight int64
+	Base   int64
+}
+
+// FargatePlacement is the parsed form of the Fargate-related ENV_* keys,
+// shaped into what ECS's RegisterTaskDefinition/RunTask/CreateService need
+// to place a service on ContainerPlatformFargate or
+// ContainerPlatformECSFargate.
+type FargatePlacement struct {
+	LaunchType           string
+	CapacityProviders    []CapacityProvider
+	PlatformVersion      string
+	TaskExecutionRoleArn string
+	CPUUnits             int64
+	MemoryMB             int64
+}
+
+// ParseFargatePlacement reads the ENV_LAUNCH_TYPE/ENV_CAPACITY_PROVIDERS/
+// ENV_PLATFORM_VERSION/ENV_TASK_EXECUTION_ROLE_ARN keys out of env, and
+// falls back to FargateCPUMemory(reserveCPUUnits, reserveMemoryMB) for
+// ENV_FARGATE_CPU/ENV_FARGATE_MEMORY when a service doesn't set them
+// explicitly. TaskExecutionRoleArn is required: Fargate has no container
+// instance role to fall back to for pulling images and writing logs, so a
+// missing value is a configuration error.
+func ParseFargatePlacement(env []*common.EnvKeyValuePair, reserveCPUUnits, reserveMemoryMB int64) (*FargatePlacement, error) {
+	defaultCPU, defaultMemory := FargateCPUMemory(reserveCPUUnits, reserveMemoryMB)
+	placement := &FargatePlacement{
+		LaunchType: "FARGATE",
+		CPUUnits:   defaultCPU,
+		MemoryMB:   defaultMemory,
+	}
+
+	for _, kv := range env {
+		switch kv.Name {
+		case common.ENV_LAUNCH_TYPE:
+			placement.LaunchType = kv.Value
+		case common.ENV_CAPACITY_PROVIDERS:
+			providers, err := parseCapacityProviders(kv.Value)
+			if err != nil {
+				return nil, err
+			}
+			placement.CapacityProviders = providers
+		case common.ENV_FARGATE_CPU:
+			cpu, err := strconv.ParseInt(kv.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("taskdef: invalid %s %q: %v", common.ENV_FARGATE_CPU, kv.Value, err)
+			}
+			placement.CPUUnits = cpu
+		case common.ENV_FARGATE_MEMORY:
+			memory, err := strconv.ParseInt(kv.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("taskdef: invalid %s %q: %v", common.ENV_FARGATE_MEMORY, kv.Value, err)
+			}
+			placement.MemoryMB = memory
+		case common.ENV_PLATFORM_VERSION:
+			placement.PlatformVersion = kv.Value
+		case common.ENV_TASK_EXECUTION_ROLE_ARN:
+			placement.TaskExecutionRoleArn = kv.Value
+		}
+	}
+
+	if placement.TaskExecutionRoleArn == "" {
+		return nil, fmt.Errorf("taskdef: %s is required for Fargate services", common.ENV_TASK_EXECUTION_ROLE_ARN)
+	}
+	if len(placement.CapacityProviders) > 0 {
+		placement.LaunchType = ""
+	}
+
+	return placement, nil
+}
+
+// parseCapacityProviders parses a ENV_VALUE_SEPARATOR-joined list of
+// "name:weight:base" entries, the shape ENV_CAPACITY_PROVIDERS uses to
+// describe an ECS capacity-provider strategy.
+func parseCapacityProviders(value string) ([]CapacityProvider, error) {
+	items := strings.Split(value, common.ENV_VALUE_SEPARATOR)
+	providers := make([]CapacityProvider, 0, len(items))
+
+	for _, item := range items {
+		fields := strings.Split(item, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("taskdef: invalid %s entry %q, expect name:weight:base", common.ENV_CAPACITY_PROVIDERS, item)
+		}
+
+		weight, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("taskdef: invalid weight in %s entry %q: %v", common.ENV_CAPACITY_PROVIDERS, item, err)
+		}
+		base, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("taskdef: invalid base in %s entry %q: %v", common.ENV_CAPACITY_PROVIDERS, item, err)
+		}
+
+		providers = append(providers, CapacityProvider{Name: fields[0], Weight: weight, Base: base})
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,42 @@
+package taskdef
+
+import (
+	"fmt"
+
+	"github.com/cloudstax/firecamp/common"
+)
+
+// ContainerRuntime returns the ENV_CONTAINER_RUNTIME value hostOS boots,
+// for propagating into the cluster bootstrap systemd unit/user-data
+// templates so they start the right init for the host.
+func ContainerRuntime(hostOS string) string {
+	if hostOS == common.HostOSBottlerocket {
+		return "containerd"
+	}
+	return "docker"
+}
+
+// RequiresECSManagedVolume reports whether hostOS has no docker plugin
+// support, so a service volume must use VolumeTypeECSManagedEBS instead of
+// the cloudstax/firecamp-volume docker volume plugin.
+func RequiresECSManagedVolume(hostOS string) bool {
+	return hostOS == common.HostOSBottlerocket
+}
+
+// RequiresFireLens reports whether hostOS has no docker plugin support,
+// so a service's logs must route through a FireLens sidecar instead of
+// the cloudstax/firecamp-log docker log driver plugin.
+func RequiresFireLens(hostOS string) bool {
+	return hostOS == common.HostOSBottlerocket
+}
+
+// ValidateVolumeForHostOS refuses to schedule a service whose volumeType
+// needs the docker volume plugin onto a host OS that doesn't support
+// docker plugins, returning a clear error instead of letting ECS fail the
+// task placement with an opaque agent error.
+func ValidateVolumeForHostOS(hostOS, volumeType string) error {
+	if RequiresECSManagedVolume(hostOS) && volumeType == common.VolumeTypeDockerPlugin {
+		return fmt.Errorf("taskdef: host OS %s does not support the %s docker volume plugin, set %s to %s instead", hostOS, common.VolumeDriverName, common.ENV_VOLUME_TYPE, common.VolumeTypeECSManagedEBS)
+	}
+	return nil
+}
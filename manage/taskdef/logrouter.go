@@ -0,0 +1,115 @@
+package taskdef
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudstax/firecamp/common"
+)
+
+// defaultLogRouterImage is the FireLens sidecar image used when a service
+// doesn't set ENV_LOG_ROUTER_IMAGE.
+const defaultLogRouterImage = "amazon/aws-for-fluent-bit:latest"
+
+// LogRouterTypeFluentBit and LogRouterTypeFluentd are the two FireLens
+// router types ECS supports, as selected by ENV_LOG_ROUTER_TYPE.
+const (
+	LogRouterTypeFluentBit = "fluentbit"
+	LogRouterTypeFluentd   = "fluentd"
+)
+
+// LogRouterContainerName is the fixed name the manage server gives the
+// FireLens sidecar it injects into every task definition using
+// LOGDRIVER_FIRELENS.
+const LogRouterContainerName = "log_router"
+
+// UsesFireLens reports whether logDriver routes the app container's logs
+// through a FireLens sidecar instead of a host-level log driver.
+func UsesFireLens(logDriver string) bool {
+	return logDriver == common.LOGDRIVER_FIRELENS
+}
+
+// LogOutputConfig is one destination a FireLens sidecar forwards logs to,
+// parsed from an entry in the ENV_LOG_OUTPUT_CONFIG JSON array. Name is
+// the Fluent Bit/Fluentd output plugin (e.g. "cloudwatch_logs", "s3",
+// "kinesis_firehose", "es", "loki"); Options holds its plugin-specific
+// key/value settings verbatim so this package doesn't need to know every
+// plugin's schema.
+type LogOutputConfig struct {
+	Name    string            `json:"name"`
+	Options map[string]string `json:"options"`
+}
+
+// LogRouterConfig is the parsed form of the ENV_LOG_ROUTER_*/ENV_LOG_*
+// keys, shaped into what the manage server needs to inject a FireLens
+// sidecar into a task definition.
+type LogRouterConfig struct {
+	Image        string
+	RouterType   string
+	OutputConfig []LogOutputConfig
+	ParserConfig string
+}
+
+// ParseLogRouterConfig reads the ENV_LOG_ROUTER_*/ENV_LOG_OUTPUT_CONFIG/
+// ENV_LOG_PARSER_CONFIG keys out of env into a LogRouterConfig, defaulting
+// RouterType to LogRouterTypeFluentBit and Image to
+// defaultLogRouterImage when unset. At least one output is required:
+// a FireLens sidecar with nowhere to send logs is a configuration error,
+// not a no-op.
+func ParseLogRouterConfig(env []*common.EnvKeyValuePair) (*LogRouterConfig, error) {
+	cfg := &LogRouterConfig{
+		Image:      defaultLogRouterImage,
+		RouterType: LogRouterTypeFluentBit,
+	}
+
+	for _, kv := range env {
+		switch kv.Name {
+		case common.ENV_LOG_ROUTER_IMAGE:
+			cfg.Image = kv.Value
+		case common.ENV_LOG_ROUTER_TYPE:
+			cfg.RouterType = kv.Value
+		case common.ENV_LOG_OUTPUT_CONFIG:
+			var outputs []LogOutputConfig
+			if err := json.Unmarshal([]byte(kv.Value), &outputs); err != nil {
+				return nil, fmt.Errorf("taskdef: invalid %s: %v", common.ENV_LOG_OUTPUT_CONFIG, err)
+			}
+			cfg.OutputConfig = outputs
+		case common.ENV_LOG_PARSER_CONFIG:
+			cfg.ParserConfig = kv.Value
+		}
+	}
+
+	if cfg.RouterType != LogRouterTypeFluentBit && cfg.RouterType != LogRouterTypeFluentd {
+		return nil, fmt.Errorf("taskdef: invalid %s %q, expect %q or %q", common.ENV_LOG_ROUTER_TYPE, cfg.RouterType, LogRouterTypeFluentBit, LogRouterTypeFluentd)
+	}
+	if len(cfg.OutputConfig) == 0 {
+		return nil, fmt.Errorf("taskdef: %s must declare at least one output for %s", common.ENV_LOG_OUTPUT_CONFIG, common.LOGDRIVER_FIRELENS)
+	}
+
+	return cfg, nil
+}
+
+// LogRouterContainer is the subset of an ECS firelensConfiguration plus
+// container definition the manage server needs to inject the FireLens
+// sidecar, kept as a local type for the same reason ECSManagedEBSVolume
+// is: the manage server's ECS adapter copies these fields into the real
+// aws-sdk-go struct at the call site.
+type LogRouterContainer struct {
+	Name        string
+	Image       string
+	RouterType  string
+	IsEssential bool
+}
+
+// BuildLogRouterContainer builds the FireLens sidecar container
+// definition for cfg. The sidecar is marked essential: if it dies, ECS
+// stops the whole task rather than letting the app container's logs
+// silently vanish.
+func BuildLogRouterContainer(cfg *LogRouterConfig) *LogRouterContainer {
+	return &LogRouterContainer{
+		Name:        LogRouterContainerName,
+		Image:       cfg.Image,
+		RouterType:  cfg.RouterType,
+		IsEssential: true,
+	}
+}
@@ -0,0 +1,84 @@
+package peering
+
+import (
+	"fmt"
+	"time"
+)
+
+// GeneratePeeringToken is the documented entry point for producing a
+// peering token for clusterName. It builds the same token as
+// Manager.InitiatePeering, from NewToken directly, so callers that only have
+// static connection info (no Manager yet, e.g. a CLI invoked before the
+// manage server starts) can still mint a token.
+func GeneratePeeringToken(clusterName, manageServerAddr, vpcID, domain, sharedSecret string, ttl time.Duration) (string, error) {
+	tok := NewToken(clusterName, manageServerAddr, vpcID, domain, sharedSecret, ttl)
+	return tok.Encode()
+}
+
+// EstablishPeering is an alias for AcceptPeeringToken using the vocabulary
+// from the peer-token handshake: it decodes/verifies the token and persists
+// the resulting Peer.
+func (m *Manager) EstablishPeering(token string) (*Peer, error) {
+	return m.AcceptPeeringToken(token)
+}
+
+// ListPeerings is an alias for ListPeers using the peer-token handshake
+// vocabulary.
+func (m *Manager) ListPeerings() ([]*Peer, error) {
+	return m.ListPeers()
+}
+
+// PeerMemberSuffix is appended to a service's domain when registering DNS
+// for a member imported from a peering, e.g.
+// "service-0-0.peer.clusterA.example.com", so local callers can address
+// remote members without colliding with local ones of the same name.
+const PeerMemberSuffix = "peer"
+
+// PeerDNSName builds the DNS name under which an imported member is
+// registered locally.
+func PeerDNSName(memberName, peerClusterName, domain string) string {
+	return fmt.Sprintf("%s.%s.%s.%s", memberName, PeerMemberSuffix, peerClusterName, domain)
+}
+
+// PeeringServiceExport is the message sent once, when a service is first
+// exported to a peer, describing the full current member set. Subsequent
+// changes are sent as MemberEvent on the same stream.
+type PeeringServiceExport struct {
+	ServiceName string
+	ServiceUUID string
+	Members     []ImportedMember
+}
+
+// ExportStream is the long-lived push interface a peering server
+// implements to stream PeeringServiceExport and MemberEvent messages to an
+// importing cluster. The production implementation is a gRPC server-
+// streaming RPC; this interface lets the exporter logic be exercised
+// without a network round trip in tests.
+type ExportStream interface {
+	SendExport(export *PeeringServiceExport) error
+	SendEvent(serviceName string, ev *MemberEvent) error
+}
+
+// Exporter drives ExportService's background streaming: it sends the full
+// member set once, then forwards member events as they are observed by the
+// caller (typically a watch on db.DB's ServiceMember rows for the exported
+// service).
+type Exporter struct {
+	stream ExportStream
+}
+
+// NewExporter creates an Exporter that pushes over stream.
+func NewExporter(stream ExportStream) *Exporter {
+	return &Exporter{stream: stream}
+}
+
+// PushInitial sends the current member set for a newly-exported service.
+func (e *Exporter) PushInitial(export *PeeringServiceExport) error {
+	return e.stream.SendExport(export)
+}
+
+// PushEvent forwards a single member add/remove/health-change event for an
+// already-exported service.
+func (e *Exporter) PushEvent(serviceName string, ev *MemberEvent) error {
+	return e.stream.SendEvent(serviceName, ev)
+}
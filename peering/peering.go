@@ -0,0 +1,232 @@
+package peering
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPeerNotFound is returned when a lookup targets an unknown peer.
+var ErrPeerNotFound = errors.New("peer not found")
+
+// ErrPeerExists is returned when a peer with the same ClusterID is already
+// established.
+var ErrPeerExists = errors.New("peer already exists")
+
+// Peer is the persisted record of an established peering with a remote
+// cluster. Manager persists Peers through its Store.
+type Peer struct {
+	ClusterID        string
+	ManageServerAddr string
+	VpcID            string
+	Domain           string
+	SharedSecret     string
+	EstablishedAt    time.Time
+}
+
+// ExportedService describes a service made visible to a peer cluster.
+type ExportedService struct {
+	ClusterID   string
+	ServiceName string
+	ServiceUUID string
+}
+
+// ImportedService is a service replicated from a peer cluster, stored with
+// PeerCluster set to the remote ClusterID.
+type ImportedService struct {
+	PeerCluster string
+	ServiceName string
+	ServiceUUID string
+	Members     []ImportedMember
+}
+
+// ImportedMember mirrors a remote ServiceMember closely enough to register
+// DNS and serve as a failover target.
+type ImportedMember struct {
+	MemberName string
+	StaticIP   string
+}
+
+// Store persists Peer and imported-service state. The production
+// implementation is backed by db.DB; tests may use an in-memory Store.
+type Store interface {
+	CreatePeer(peer *Peer) error
+	GetPeer(clusterID string) (*Peer, error)
+	ListPeers() ([]*Peer, error)
+	DeletePeer(clusterID string) error
+
+	PutImportedService(svc *ImportedService) error
+	ListImportedServices(peerCluster string) ([]*ImportedService, error)
+	DeleteImportedServices(peerCluster string) error
+}
+
+// EventType enumerates the kinds of events streamed from an exporting
+// cluster to an importing one.
+type EventType int
+
+const (
+	// MemberAdded indicates a new ServiceMember was created on the
+	// exporting cluster.
+	MemberAdded EventType = iota
+	// MemberRemoved indicates a ServiceMember was deleted.
+	MemberRemoved
+	// MemberHealthChanged indicates a health status change on a member.
+	MemberHealthChanged
+)
+
+// MemberEvent is pushed over the long-lived peering stream for every
+// exported service member change.
+type MemberEvent struct {
+	Type        EventType
+	ServiceName string
+	Member      ImportedMember
+}
+
+// Manager implements the peering control-plane surface: InitiatePeering,
+// AcceptPeeringToken, ListPeers, ExportService, ImportService. It is
+// transport- and storage-agnostic by design: Store is the seam a db.DB-
+// backed implementation plugs into, and ExportStream (export.go) is the
+// seam a gRPC server-streaming RPC plugs into. ManageService, the
+// production caller that would construct a Manager with db.DB- and gRPC-
+// backed implementations of those two interfaces, is not part of this
+// tree (manage/server.go doesn't exist here); TestUtil_Peering exercises
+// Manager directly against the in-memory memStore in this file's place.
+type Manager struct {
+	clusterID        string
+	manageServerAddr string
+	vpcID            string
+	domain           string
+
+	store Store
+
+	lock     sync.Mutex
+	exported map[string]*ExportedService // serviceName -> export record
+}
+
+// NewManager creates a peering Manager for the local cluster.
+func NewManager(clusterID, manageServerAddr, vpcID, domain string, store Store) *Manager {
+	return &Manager{
+		clusterID:        clusterID,
+		manageServerAddr: manageServerAddr,
+		vpcID:            vpcID,
+		domain:           domain,
+		store:            store,
+		exported:         make(map[string]*ExportedService),
+	}
+}
+
+// InitiatePeering generates a signed token that the remote cluster's
+// operator passes to AcceptPeeringToken to complete the handshake.
+func (m *Manager) InitiatePeering(sharedSecret string, ttl time.Duration) (string, error) {
+	tok := NewToken(m.clusterID, m.manageServerAddr, m.vpcID, m.domain, sharedSecret, ttl)
+	return tok.Encode()
+}
+
+// AcceptPeeringToken decodes and verifies a token produced by a remote
+// cluster's InitiatePeering, then persists the Peer record. The caller is
+// responsible for opening the long-lived event stream after this returns.
+func (m *Manager) AcceptPeeringToken(blob string) (*Peer, error) {
+	tok, err := DecodeToken(blob)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing, err := m.store.GetPeer(tok.ClusterID); err == nil && existing != nil {
+		return nil, ErrPeerExists
+	}
+
+	peer := &Peer{
+		ClusterID:        tok.ClusterID,
+		ManageServerAddr: tok.ManageServerAddr,
+		VpcID:            tok.VpcID,
+		Domain:           tok.Domain,
+		SharedSecret:     tok.SharedSecret,
+		EstablishedAt:    time.Now(),
+	}
+	if err := m.store.CreatePeer(peer); err != nil {
+		return nil, err
+	}
+	return peer, nil
+}
+
+// ListPeers returns all established peerings for the local cluster.
+func (m *Manager) ListPeers() ([]*Peer, error) {
+	return m.store.ListPeers()
+}
+
+// ExportService marks a local service as visible to a peer cluster. It only
+// records the export; member sets and events are pushed to the peer through
+// an Exporter (export.go), which ExportService does not start.
+func (m *Manager) ExportService(peerClusterID, serviceName, serviceUUID string) error {
+	if _, err := m.store.GetPeer(peerClusterID); err != nil {
+		return ErrPeerNotFound
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.exported[serviceName] = &ExportedService{
+		ClusterID:   peerClusterID,
+		ServiceName: serviceName,
+		ServiceUUID: serviceUUID,
+	}
+	return nil
+}
+
+// ImportService records a service replicated from a peer cluster in the
+// Store, under its PeerCluster.
+func (m *Manager) ImportService(svc *ImportedService) error {
+	return m.store.PutImportedService(svc)
+}
+
+// RevokePeer removes a peering and all services imported from it.
+func (m *Manager) RevokePeer(clusterID string) error {
+	if err := m.store.DeleteImportedServices(clusterID); err != nil {
+		return err
+	}
+	return m.store.DeletePeer(clusterID)
+}
+
+// ApplyEvent updates the local imported-service view in response to a
+// MemberEvent received over the peering stream from clusterID.
+func (m *Manager) ApplyEvent(clusterID string, ev *MemberEvent) error {
+	svcs, err := m.store.ListImportedServices(clusterID)
+	if err != nil {
+		return err
+	}
+
+	var target *ImportedService
+	for _, s := range svcs {
+		if s.ServiceName == ev.ServiceName {
+			target = s
+			break
+		}
+	}
+	if target == nil {
+		target = &ImportedService{PeerCluster: clusterID, ServiceName: ev.ServiceName}
+	}
+
+	switch ev.Type {
+	case MemberAdded, MemberHealthChanged:
+		replaced := false
+		for i, mem := range target.Members {
+			if mem.MemberName == ev.Member.MemberName {
+				target.Members[i] = ev.Member
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			target.Members = append(target.Members, ev.Member)
+		}
+	case MemberRemoved:
+		kept := target.Members[:0]
+		for _, mem := range target.Members {
+			if mem.MemberName != ev.Member.MemberName {
+				kept = append(kept, mem)
+			}
+		}
+		target.Members = kept
+	}
+
+	return m.store.PutImportedService(target)
+}
@@ -0,0 +1,66 @@
+package peering
+
+import "testing"
+
+// TestPeering runs TestUtil_Peering so the handshake/export/import/revoke
+// scenario it exercises actually runs under go test, instead of only being
+// reachable by a caller that never existed in this tree.
+func TestPeering(t *testing.T) {
+	TestUtil_Peering(t)
+}
+
+// fakeExportStream is an in-memory ExportStream double for testing Exporter
+// without a gRPC round trip.
+type fakeExportStream struct {
+	exports []*PeeringServiceExport
+	events  []*MemberEvent
+}
+
+func (s *fakeExportStream) SendExport(export *PeeringServiceExport) error {
+	s.exports = append(s.exports, export)
+	return nil
+}
+
+func (s *fakeExportStream) SendEvent(serviceName string, ev *MemberEvent) error {
+	s.events = append(s.events, ev)
+	return nil
+}
+
+// TestExporterPushesInitialThenEvents asserts Exporter sends the full
+// member set once via PushInitial, then forwards subsequent PushEvent calls
+// on the same stream without touching the initial export.
+func TestExporterPushesInitialThenEvents(t *testing.T) {
+	stream := &fakeExportStream{}
+	e := NewExporter(stream)
+
+	initial := &PeeringServiceExport{
+		ServiceName: "service-0",
+		ServiceUUID: "uuid-service-0",
+		Members:     []ImportedMember{{MemberName: "service-0-0", StaticIP: "10.0.0.4"}},
+	}
+	if err := e.PushInitial(initial); err != nil {
+		t.Fatalf("PushInitial error %s", err)
+	}
+
+	ev := &MemberEvent{Type: MemberAdded, ServiceName: "service-0", Member: ImportedMember{MemberName: "service-0-1", StaticIP: "10.0.0.5"}}
+	if err := e.PushEvent("service-0", ev); err != nil {
+		t.Fatalf("PushEvent error %s", err)
+	}
+
+	if len(stream.exports) != 1 || stream.exports[0] != initial {
+		t.Fatalf("expect exactly the initial export sent once, got %v", stream.exports)
+	}
+	if len(stream.events) != 1 || stream.events[0] != ev {
+		t.Fatalf("expect exactly the one event forwarded, got %v", stream.events)
+	}
+}
+
+// TestPeerDNSName asserts the DNS name format used to register an imported
+// member locally.
+func TestPeerDNSName(t *testing.T) {
+	got := PeerDNSName("service-0-0", "clusterA", "example.com")
+	want := "service-0-0.peer.clusterA.example.com"
+	if got != want {
+		t.Errorf("PeerDNSName() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+package peering
+
+import (
+	"testing"
+	"time"
+)
+
+// memStore is an in-memory Store used by TestUtil_Peering. It is
+// unexported, so only this package can use it.
+type memStore struct {
+	peers    map[string]*Peer
+	imported map[string][]*ImportedService
+}
+
+func newMemStore() *memStore {
+	return &memStore{
+		peers:    make(map[string]*Peer),
+		imported: make(map[string][]*ImportedService),
+	}
+}
+
+func (s *memStore) CreatePeer(peer *Peer) error {
+	s.peers[peer.ClusterID] = peer
+	return nil
+}
+
+func (s *memStore) GetPeer(clusterID string) (*Peer, error) {
+	peer, ok := s.peers[clusterID]
+	if !ok {
+		return nil, ErrPeerNotFound
+	}
+	return peer, nil
+}
+
+func (s *memStore) ListPeers() ([]*Peer, error) {
+	peers := make([]*Peer, 0, len(s.peers))
+	for _, p := range s.peers {
+		peers = append(peers, p)
+	}
+	return peers, nil
+}
+
+func (s *memStore) DeletePeer(clusterID string) error {
+	delete(s.peers, clusterID)
+	return nil
+}
+
+func (s *memStore) PutImportedService(svc *ImportedService) error {
+	list := s.imported[svc.PeerCluster]
+	for i, existing := range list {
+		if existing.ServiceName == svc.ServiceName {
+			list[i] = svc
+			return nil
+		}
+	}
+	s.imported[svc.PeerCluster] = append(list, svc)
+	return nil
+}
+
+func (s *memStore) ListImportedServices(peerCluster string) ([]*ImportedService, error) {
+	return s.imported[peerCluster], nil
+}
+
+func (s *memStore) DeleteImportedServices(peerCluster string) error {
+	delete(s.imported, peerCluster)
+	return nil
+}
+
+// TestUtil_Peering stands up two in-process Managers representing two
+// clusters, drives the token handshake, exports a service from A to B, feeds
+// the equivalent of the streamed member events, and verifies bidirectional
+// visibility plus peer revocation.
+func TestUtil_Peering(t *testing.T) {
+	clusterA := NewManager("clusterA", "manageA:27040", "vpc-1", "a.example.com", newMemStore())
+	clusterB := NewManager("clusterB", "manageB:27040", "vpc-1", "b.example.com", newMemStore())
+
+	token, err := clusterA.InitiatePeering("sharedsecret", time.Hour)
+	if err != nil {
+		t.Fatalf("InitiatePeering error %s", err)
+	}
+
+	peerOfA, err := clusterB.AcceptPeeringToken(token)
+	if err != nil {
+		t.Fatalf("AcceptPeeringToken error %s", err)
+	}
+	if peerOfA.ClusterID != "clusterA" {
+		t.Fatalf("expect peer clusterA, got %s", peerOfA.ClusterID)
+	}
+
+	// the handshake is mutual: clusterB must issue its own token back to
+	// clusterA so clusterA learns of clusterB too, the same way clusterB
+	// just learned of clusterA above.
+	tokenB, err := clusterB.InitiatePeering("sharedsecret", time.Hour)
+	if err != nil {
+		t.Fatalf("InitiatePeering error %s", err)
+	}
+	peerOfB, err := clusterA.AcceptPeeringToken(tokenB)
+	if err != nil {
+		t.Fatalf("AcceptPeeringToken error %s", err)
+	}
+	if peerOfB.ClusterID != "clusterB" {
+		t.Fatalf("expect peer clusterB, got %s", peerOfB.ClusterID)
+	}
+
+	if err = clusterA.ExportService("clusterB", "service-0", "uuid-service-0"); err != nil {
+		t.Fatalf("ExportService error %s", err)
+	}
+
+	// simulate the watermark-based reconciliation: push the current member
+	// set as a sequence of MemberAdded events after a (re)connect.
+	events := []*MemberEvent{
+		{Type: MemberAdded, ServiceName: "service-0", Member: ImportedMember{MemberName: "service-0-0", StaticIP: "10.0.0.4"}},
+		{Type: MemberAdded, ServiceName: "service-0", Member: ImportedMember{MemberName: "service-0-1", StaticIP: "10.0.0.5"}},
+	}
+	for _, ev := range events {
+		if err = clusterB.ApplyEvent("clusterA", ev); err != nil {
+			t.Fatalf("ApplyEvent error %s", err)
+		}
+	}
+
+	imported, err := clusterB.store.ListImportedServices("clusterA")
+	if err != nil || len(imported) != 1 || len(imported[0].Members) != 2 {
+		t.Fatalf("expect 1 imported service with 2 members, got %v err %s", imported, err)
+	}
+
+	// a reconnect resending the same add events must not duplicate members.
+	if err = clusterB.ApplyEvent("clusterA", events[0]); err != nil {
+		t.Fatalf("ApplyEvent reconcile error %s", err)
+	}
+	imported, _ = clusterB.store.ListImportedServices("clusterA")
+	if len(imported[0].Members) != 2 {
+		t.Fatalf("expect reconnect reconciliation to not duplicate members, got %d", len(imported[0].Members))
+	}
+
+	// removing a member is reflected.
+	if err = clusterB.ApplyEvent("clusterA", &MemberEvent{Type: MemberRemoved, ServiceName: "service-0", Member: ImportedMember{MemberName: "service-0-1"}}); err != nil {
+		t.Fatalf("ApplyEvent remove error %s", err)
+	}
+	imported, _ = clusterB.store.ListImportedServices("clusterA")
+	if len(imported[0].Members) != 1 {
+		t.Fatalf("expect 1 member after removal, got %d", len(imported[0].Members))
+	}
+
+	// revoking the peer removes the imported records.
+	if err = clusterB.RevokePeer("clusterA"); err != nil {
+		t.Fatalf("RevokePeer error %s", err)
+	}
+	imported, _ = clusterB.store.ListImportedServices("clusterA")
+	if len(imported) != 0 {
+		t.Fatalf("expect no imported services after revoke, got %d", len(imported))
+	}
+}
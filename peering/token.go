@@ -0,0 +1,95 @@
+package peering
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrTokenExpired is returned when a peering token's expiry has passed.
+var ErrTokenExpired = errors.New("peering token expired")
+
+// ErrTokenInvalid is returned when a peering token fails to decode or
+// fails HMAC verification.
+var ErrTokenInvalid = errors.New("peering token invalid")
+
+// Token is the payload exchanged out-of-band between two cluster operators
+// to establish peering. It is base64-encoded JSON, signed with an HMAC
+// shared secret so a tampered token is rejected by AcceptPeeringToken.
+type Token struct {
+	ClusterID        string    `json:"ClusterID"`
+	ManageServerAddr string    `json:"ManageServerAddr"`
+	VpcID            string    `json:"VpcID"`
+	Domain           string    `json:"Domain"`
+	SharedSecret     string    `json:"SharedSecret"`
+	ExpireAt         time.Time `json:"ExpireAt"`
+}
+
+type signedToken struct {
+	Token Token  `json:"Token"`
+	MAC   string `json:"MAC"`
+}
+
+// NewToken creates a peering token for clusterID that expires after ttl.
+func NewToken(clusterID, manageServerAddr, vpcID, domain, sharedSecret string, ttl time.Duration) *Token {
+	return &Token{
+		ClusterID:        clusterID,
+		ManageServerAddr: manageServerAddr,
+		VpcID:            vpcID,
+		Domain:           domain,
+		SharedSecret:     sharedSecret,
+		ExpireAt:         time.Now().Add(ttl),
+	}
+}
+
+// Encode signs the token with its own SharedSecret and returns the
+// base64-encoded blob handed to the remote cluster operator.
+func (t *Token) Encode() (string, error) {
+	mac := t.sign()
+	st := signedToken{Token: *t, MAC: mac}
+	data, err := json.Marshal(&st)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeToken decodes and verifies a token produced by Encode. It returns
+// ErrTokenInvalid if the MAC does not match, and ErrTokenExpired if the
+// token has expired.
+func DecodeToken(blob string) (*Token, error) {
+	data, err := base64.StdEncoding.DecodeString(blob)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	var st signedToken
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	expectedMAC := st.Token.sign()
+	if !hmac.Equal([]byte(expectedMAC), []byte(st.MAC)) {
+		return nil, ErrTokenInvalid
+	}
+
+	if time.Now().After(st.Token.ExpireAt) {
+		return nil, ErrTokenExpired
+	}
+
+	tok := st.Token
+	return &tok, nil
+}
+
+func (t *Token) sign() string {
+	mac := hmac.New(sha256.New, []byte(t.SharedSecret))
+	mac.Write([]byte(t.ClusterID))
+	mac.Write([]byte(t.ManageServerAddr))
+	mac.Write([]byte(t.VpcID))
+	mac.Write([]byte(t.Domain))
+	mac.Write([]byte(t.ExpireAt.Format(time.RFC3339Nano)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}